@@ -0,0 +1,348 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+// AutoscalingOptions configures the autoscaling of workers
+type AutoscalingOptions struct {
+	// MinWorkers is the minimum number of workers, which should be at least 1
+	MinWorkers int
+	// MaxWorkers is the maximum number of workers
+	MaxWorkers int
+	// Interval is the period to evaluate the number of workers. Defaults to 5s.
+	Interval time.Duration
+	// ItemsPerWorker is the queue depth each worker is expected to keep up with. Workers are added once the
+	// queue grows deeper than that. Defaults to 10.
+	ItemsPerWorker int
+	// MaxLatency is the acceptable average time for an enqueued work item to wait before being processed.
+	// Workers are added once the latency rises above that. Defaults to 1s.
+	MaxLatency time.Duration
+}
+
+// desiredWorkers calculates the number of workers based on the queue depth, the average wait latency and the
+// number of busy workers
+func (o *AutoscalingOptions) desiredWorkers(current, depth, busy int, latency time.Duration) int {
+	desired := current
+	switch {
+	case depth > current*o.ItemsPerWorker || latency > o.MaxLatency:
+		desired = max(current+1, (depth+o.ItemsPerWorker-1)/o.ItemsPerWorker)
+	case depth == 0 && busy < current:
+		// shrink gradually when idle
+		desired = max(busy, current/2)
+	}
+	return o.clamp(desired)
+}
+
+func (o *AutoscalingOptions) clamp(workers int) int {
+	return min(max(workers, o.MinWorkers), o.MaxWorkers)
+}
+
+// WithAutoscaling scales the workers between opts.MinWorkers and opts.MaxWorkers. Workers are added when the
+// queue depth or the wait latency of enqueued work items rises, and removed when idle.
+func (c *TypedController[K]) WithAutoscaling(opts AutoscalingOptions) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate autoscaling when controller %s is running", c.name))
+	}
+	if opts.MinWorkers < 1 || opts.MaxWorkers < opts.MinWorkers {
+		panic(fmt.Errorf("invalid worker bounds [%d, %d] for autoscaling", opts.MinWorkers, opts.MaxWorkers))
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.ItemsPerWorker <= 0 {
+		opts.ItemsPerWorker = 10
+	}
+	if opts.MaxLatency <= 0 {
+		opts.MaxLatency = time.Second
+	}
+	c.autoscaling = &opts
+	return c
+}
+
+// SetWorkers changes the number of workers, which is safe to call while the controller is running. Idle workers
+// exit at once when there are too many, while busy ones exit after finishing the work items in hand. With
+// autoscaling, workers are bounded by the autoscaling options and may be changed again by the autoscaler later.
+func (c *TypedController[K]) SetWorkers(workers int) {
+	if workers < 0 {
+		panic(fmt.Errorf("can not set negative workers %d", workers))
+	}
+	if c.autoscaling != nil {
+		workers = c.autoscaling.clamp(workers)
+	}
+
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+	c.workers = utilpointer.Int(workers)
+	if c.pool != nil {
+		c.pool.resize(workers)
+	}
+}
+
+// numWorkers returns the current number of workers
+func (c *TypedController[K]) numWorkers() int {
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+	return *c.workers
+}
+
+// startWorkers launches a pool of workers, which is stopped once ctx is cancelled
+func (c *TypedController[K]) startWorkers(ctx context.Context) *workerPool[K] {
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+
+	if c.autoscaling != nil {
+		c.workers = utilpointer.Int(c.autoscaling.clamp(*c.workers))
+	}
+	c.pool = newWorkerPool(ctx, c.dispatch, c.runWorker)
+	c.pool.resize(*c.workers)
+	if c.autoscaling != nil {
+		go wait.UntilWithContext(ctx, c.autoscale, c.autoscaling.Interval)
+	}
+	return c.pool
+}
+
+// stopWorkers stops handing out new work items to the workers, which exit after finishing the work items in hand
+func (c *TypedController[K]) stopWorkers() {
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+	c.pool.close()
+	c.pool = nil
+}
+
+// autoscale evaluates the number of workers once
+func (c *TypedController[K]) autoscale(_ context.Context) {
+	current := c.numWorkers()
+	depth := c.queue.Len()
+	latency := c.latency.average()
+	desired := c.autoscaling.desiredWorkers(current, depth, int(c.busyWorkers.Load()), latency)
+	if desired == current {
+		return
+	}
+
+	klog.V(4).Infof("scaling workers of controller %s from %d to %d with queue depth %d and latency %v",
+		c.name, current, desired, depth, latency)
+	c.SetWorkers(desired)
+}
+
+// markEnqueued records the time when the key is enqueued for the first time since last processed
+func (c *TypedController[K]) markEnqueued(key K) {
+	if c.autoscaling != nil {
+		c.enqueuedAt.LoadOrStore(key, time.Now())
+	}
+}
+
+// observeDequeued records how long the key has been waiting in the queue
+func (c *TypedController[K]) observeDequeued(key K) {
+	if c.autoscaling == nil {
+		return
+	}
+	if value, ok := c.enqueuedAt.LoadAndDelete(key); ok {
+		c.latency.observe(time.Since(value.(time.Time)))
+	}
+}
+
+// latencyTracker averages the wait latency of work items between two reads
+type latencyTracker struct {
+	total atomic.Int64
+	count atomic.Int64
+}
+
+func (t *latencyTracker) observe(d time.Duration) {
+	t.total.Add(int64(d))
+	t.count.Add(1)
+}
+
+func (t *latencyTracker) average() time.Duration {
+	total, count := t.total.Swap(0), t.count.Swap(0)
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(total / count)
+}
+
+// workerPool runs a resizable number of workers. Work items are handed out to the workers by a single dispatcher,
+// which reads a work item only when a worker is ready to take it, so that idle workers are able to exit without
+// waiting for the next work items, and no work item is held back without workers.
+type workerPool[K comparable] struct {
+	// ctx is passed to the workers
+	ctx context.Context
+	// dispatchCtx is cancelled once the pool is closed, which stops handing out new work items
+	dispatchCtx  context.Context
+	stopDispatch context.CancelFunc
+	// idle receives the idle workers, each of which waits for a single work item
+	idle chan *idleWorker[K]
+	work func(ctx context.Context, pool *workerPool[K])
+
+	lock   sync.Mutex
+	target int
+	// active is the number of workers not retired, while alive is the number of workers not exited
+	active int
+	alive  int
+	// shrunk is closed to wake up the idle workers once the pool shrinks
+	shrunk chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newWorkerPool starts the dispatcher, which hands out work items with the pool until the pool is closed
+func newWorkerPool[K comparable](ctx context.Context, dispatch func(ctx context.Context, pool *workerPool[K]),
+	work func(ctx context.Context, pool *workerPool[K])) *workerPool[K] {
+	p := &workerPool[K]{
+		ctx:    ctx,
+		idle:   make(chan *idleWorker[K]),
+		work:   work,
+		shrunk: make(chan struct{}),
+	}
+	p.dispatchCtx, p.stopDispatch = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		dispatch(p.dispatchCtx, p)
+	}()
+	return p
+}
+
+// resize launches workers up to n. Extra workers exit once idle.
+func (p *workerPool[K]) resize(n int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.dispatchCtx.Err() != nil {
+		return
+	}
+
+	if n < p.target {
+		close(p.shrunk)
+		p.shrunk = make(chan struct{})
+	}
+	p.target = n
+	for ; p.active < p.target; p.active++ {
+		p.alive++
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer p.exit()
+			p.work(p.ctx, p)
+		}()
+	}
+}
+
+// exit records the exit of a worker
+func (p *workerPool[K]) exit() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.alive--
+}
+
+// workers returns the number of alive workers and the target number of workers
+func (p *workerPool[K]) workers() (alive, target int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.alive, p.target
+}
+
+// nextIdle blocks until a worker is ready to take a work item. It returns false if the pool is closed.
+func (p *workerPool[K]) nextIdle() (*idleWorker[K], bool) {
+	select {
+	case worker := <-p.idle:
+		return worker, true
+	case <-p.dispatchCtx.Done():
+		return nil, false
+	}
+}
+
+// next blocks until the calling worker gets a work item. It returns false once the worker should exit, which
+// happens when the pool is closed or shrinks.
+func (p *workerPool[K]) next() (K, bool) {
+	var zero K
+	for {
+		shrunk, retired := p.retire()
+		if retired {
+			return zero, false
+		}
+
+		worker := &idleWorker[K]{items: make(chan K, 1)}
+		select {
+		case p.idle <- worker:
+			select {
+			case item, ok := <-worker.items:
+				return item, ok
+			case <-shrunk:
+				if !worker.withdraw() {
+					// the work item has been handed out
+					item, ok := <-worker.items
+					return item, ok
+				}
+			}
+		case <-shrunk:
+		case <-p.dispatchCtx.Done():
+			return zero, false
+		}
+	}
+}
+
+// retire checks whether the calling worker should exit. Otherwise, it returns the channel closed on shrinking.
+func (p *workerPool[K]) retire() (<-chan struct{}, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.active > p.target {
+		p.active--
+		return nil, true
+	}
+	return p.shrunk, false
+}
+
+// close stops handing out new work items and launching new workers
+func (p *workerPool[K]) close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stopDispatch()
+}
+
+// wait blocks until the dispatcher and all the workers exit
+func (p *workerPool[K]) wait() {
+	p.wg.Wait()
+}
+
+// idleWorker is a worker waiting for a single work item from the dispatcher, which may withdraw when the pool shrinks
+type idleWorker[K comparable] struct {
+	lock      sync.Mutex
+	items     chan K
+	withdrawn bool
+}
+
+// hand hands out the work item to the worker. It returns false if the worker has withdrawn.
+func (w *idleWorker[K]) hand(item K) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.withdrawn {
+		return false
+	}
+	w.items <- item
+	return true
+}
+
+// cancel tells the worker that no work item will be handed out
+func (w *idleWorker[K]) cancel() {
+	close(w.items)
+}
+
+// withdraw stops waiting for the work item. It returns false if the work item has already been handed out.
+func (w *idleWorker[K]) withdraw() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.items) > 0 {
+		return false
+	}
+	w.withdrawn = true
+	return true
+}
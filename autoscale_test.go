@@ -0,0 +1,123 @@
+package yacht_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestSetWorkers(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 10)
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, key string) (*time.Duration, error) {
+		started <- key
+		<-release
+		return nil, nil
+	})
+	h.Controller.WithWorkers(1)
+	stop := run(t, h)
+	defer stop()
+
+	h.Controller.SetWorkers(3)
+	eventually(t, "3 workers", func() bool { return h.Controller.AliveWorkers() == 3 })
+	for _, key := range []string{"a", "b", "c"} {
+		h.Controller.Enqueue(namespace(key))
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	// busy workers exit after finishing the work items in hand
+	h.Controller.SetWorkers(1)
+	if alive := h.Controller.AliveWorkers(); alive != 3 {
+		t.Fatalf("expected busy workers to keep running, got %d alive", alive)
+	}
+	close(release)
+	eventually(t, "busy workers to exit", func() bool { return h.Controller.AliveWorkers() == 1 })
+	if err := h.Controller.Healthy(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetWorkersRetiresIdleWorkers(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Controller.WithWorkers(1)
+	stop := run(t, h)
+	defer stop()
+
+	h.Controller.SetWorkers(5)
+	eventually(t, "5 workers", func() bool { return h.Controller.AliveWorkers() == 5 })
+	h.Controller.SetWorkers(1)
+	eventually(t, "idle workers to exit", func() bool { return h.Controller.AliveWorkers() == 1 })
+
+	// the remaining worker still works
+	h.Controller.Enqueue(namespace("a"))
+	eventually(t, "the work item to be processed", func() bool { return len(h.Calls()) == 1 })
+}
+
+func TestSetWorkersToZero(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	stop := run(t, h)
+
+	h.Controller.SetWorkers(0)
+	eventually(t, "all workers to exit", func() bool { return h.Controller.AliveWorkers() == 0 })
+	h.Controller.Enqueue(namespace("a"))
+	time.Sleep(50 * time.Millisecond)
+	if calls := len(h.Calls()); calls != 0 {
+		t.Fatalf("expected no calls without workers, got %d", calls)
+	}
+	// the work item is left in the queue
+	if depth := h.Queue.Len(); depth != 1 {
+		t.Fatalf("expected the work item to stay in the queue without workers, got depth %d", depth)
+	}
+
+	h.Controller.SetWorkers(2)
+	eventually(t, "the work item to be processed", func() bool { return len(h.Calls()) == 1 })
+	stop()
+	if alive := h.Controller.AliveWorkers(); alive != 0 {
+		t.Fatalf("expected all workers to exit after stopping, got %d alive", alive)
+	}
+}
+
+func TestStartWithZeroWorkers(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Controller.WithWorkers(0)
+	stop := run(t, h)
+	defer stop()
+
+	h.Controller.Enqueue(namespace("a"))
+	h.Controller.Enqueue(namespace("b"))
+	time.Sleep(50 * time.Millisecond)
+	if depth := h.Queue.Len(); depth != 2 {
+		t.Fatalf("expected the work items to stay in the queue without workers, got depth %d", depth)
+	}
+	h.Controller.SetWorkers(1)
+	eventually(t, "the work items to be processed", func() bool { return len(h.Calls()) == 2 })
+}
+
+func TestAutoscaling(t *testing.T) {
+	release := make(chan struct{})
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		<-release
+		return nil, nil
+	})
+	h.Controller.WithAutoscaling(yacht.AutoscalingOptions{
+		MinWorkers:     1,
+		MaxWorkers:     4,
+		Interval:       10 * time.Millisecond,
+		ItemsPerWorker: 1,
+	})
+	stop := run(t, h)
+	defer stop()
+
+	for i := 0; i < 10; i++ {
+		h.Controller.Enqueue(namespace(fmt.Sprintf("ns-%d", i)))
+	}
+	eventually(t, "workers to scale up", func() bool { return h.Controller.AliveWorkers() == 4 })
+	close(release)
+	eventually(t, "workers to scale down when idle", func() bool { return h.Controller.AliveWorkers() == 1 })
+}
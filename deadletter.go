@@ -0,0 +1,57 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// ReasonDeadLetter is the reason of the event recorded when a work item exceeds the max retries
+const ReasonDeadLetter = "DeadLetter"
+
+// TypedDeadLetterFunc handles a work item which is given up after exceeding the max retries, with the last error
+type TypedDeadLetterFunc[K comparable] func(ctx context.Context, key K, lastErr error)
+
+type DeadLetterFunc = TypedDeadLetterFunc[interface{}]
+
+// WithMaxRetries limits the number of retries of a failed work item, no matter whether it is retried fast, with
+// backoff or after Result.RequeueAfter. Once a work item has been retried maxRetries times in a row, it is
+// forgotten and passed to deadLetterFunc. The dead letter is always logged and
+// recorded as a Warning event if the event recorder is set, so deadLetterFunc can be nil.
+// Zero maxRetries means retrying forever.
+func (c *TypedController[K]) WithMaxRetries(maxRetries int, deadLetterFunc TypedDeadLetterFunc[K]) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate maxRetries when controller %s is running", c.name))
+	}
+	if maxRetries < 0 {
+		panic(fmt.Errorf("can not set negative maxRetries %d", maxRetries))
+	}
+
+	c.maxRetries = maxRetries
+	c.deadLetterFunc = deadLetterFunc
+	return c
+}
+
+// exceedsMaxRetries checks whether the work item has used up its retry budget
+func (c *TypedController[K]) exceedsMaxRetries(item K) bool {
+	return c.maxRetries > 0 && c.failures.get(item) >= c.maxRetries
+}
+
+// deadLetter gives up the work item
+func (c *TypedController[K]) deadLetter(ctx context.Context, item K, involvedObject runtime.Object, lastErr error) {
+	klog.Errorf("controller %s gives up work item %v after %d retries: %v", c.name, item, c.maxRetries, lastErr)
+	if c.recorder != nil && involvedObject != nil {
+		c.recorder.Eventf(involvedObject, corev1.EventTypeWarning, ReasonDeadLetter,
+			"Given up by controller %s after %d retries: %v", c.name, c.maxRetries, lastErr)
+	}
+
+	c.queue.Forget(item)
+	c.failures.forget(item)
+	c.forgetObject(item, involvedObject)
+	if c.deadLetterFunc != nil {
+		c.deadLetterFunc(ctx, item, lastErr)
+	}
+}
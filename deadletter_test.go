@@ -0,0 +1,67 @@
+package yacht_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestDeadLetterEvent(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		return nil, errors.New("failed")
+	})
+	recorder := record.NewFakeRecorder(10)
+	h.Controller.WithEventRecorder(recorder, &yacht.EventOptions{}).WithMaxRetries(1, nil)
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+	h.Flush()
+	h.ProcessAll(context.Background())
+
+	if len(h.Queue.RateLimited()) != 0 {
+		t.Fatal("expected the dead letter not to be retried")
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, yacht.ReasonDeadLetter) {
+			t.Fatalf("unexpected event %s", event)
+		}
+	default:
+		t.Fatal("expected an event for the dead letter")
+	}
+}
+
+func TestRetryBudgetResetsOnSuccess(t *testing.T) {
+	var calls int
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		calls++
+		// fails twice in a row between successes
+		if calls%3 != 0 {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	var deadLetters int
+	h.Controller.WithMaxRetries(2, func(_ context.Context, _ string, _ error) {
+		deadLetters++
+	})
+	h.Add(namespace("a"))
+	for i := 0; i < 3; i++ {
+		h.ProcessAll(context.Background())
+		h.Flush()
+		h.ProcessAll(context.Background())
+		h.Flush()
+		h.ProcessAll(context.Background())
+		h.Add(namespace("a"))
+	}
+
+	if calls != 9 || deadLetters != 0 {
+		t.Fatalf("expected 9 calls without dead letters, got %d calls and %d dead letters", calls, deadLetters)
+	}
+}
@@ -0,0 +1,86 @@
+package yacht
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// singleKey converts an enqueueFunc into an enqueueKeysFunc
+func singleKey[K comparable](enqueueFunc TypedEnqueueFunc[interface{}, K]) TypedEnqueueKeysFunc[interface{}, K] {
+	return func(obj interface{}) ([]K, error) {
+		key, err := enqueueFunc(obj)
+		if err != nil {
+			return nil, err
+		}
+		return []K{key}, nil
+	}
+}
+
+// EnqueueMapped fans out an object to the keys returned by mapFunc
+func EnqueueMapped[K comparable](mapFunc func(obj interface{}) []K) TypedEnqueueKeysFunc[interface{}, K] {
+	return func(obj interface{}) ([]K, error) {
+		return mapFunc(obj), nil
+	}
+}
+
+// EnqueueOwner maps an object to the keys of its owners of ownerGVK by walking its OwnerReferences. Only the
+// group and kind are compared. If isController is true, only the controller owner is considered.
+// Owners are assumed to be in the same namespace as the object, since cross-namespace owner references are
+// disallowed. K should be string, interface{} or types.NamespacedName.
+func EnqueueOwner[K comparable](ownerGVK schema.GroupVersionKind, isController bool) TypedEnqueueKeysFunc[interface{}, K] {
+	return func(obj interface{}) ([]K, error) {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []K
+		for _, ref := range accessor.GetOwnerReferences() {
+			if isController && (ref.Controller == nil || !*ref.Controller) {
+				continue
+			}
+			gv, parseErr := schema.ParseGroupVersion(ref.APIVersion)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			if gv.Group != ownerGVK.Group || ref.Kind != ownerGVK.Kind {
+				continue
+			}
+
+			key, keyErr := namespacedKey[K](accessor.GetNamespace(), ref.Name)
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+}
+
+// namespacedKey converts namespace and name into a key of type K
+func namespacedKey[K comparable](namespace, name string) (K, error) {
+	var key K
+	nn := types.NamespacedName{Namespace: namespace, Name: name}
+	switch k := any(&key).(type) {
+	case *string:
+		*k = keyString(nn)
+	case *interface{}:
+		*k = keyString(nn)
+	case *types.NamespacedName:
+		*k = nn
+	default:
+		return key, fmt.Errorf("can not convert %s into key of type %T", nn, key)
+	}
+	return key, nil
+}
+
+// keyString uses the format <namespace>/<name> unless <namespace> is empty, then it's just <name>
+func keyString(nn types.NamespacedName) string {
+	if len(nn.Namespace) == 0 {
+		return nn.Name
+	}
+	return nn.String()
+}
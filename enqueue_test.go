@@ -0,0 +1,105 @@
+package yacht_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+var replicaSetGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+
+func ownedPod() *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "pod",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "controller", Controller: ptr.To(true)},
+			// the version is not compared
+			{APIVersion: "apps/v1beta2", Kind: "ReplicaSet", Name: "owner"},
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "other-kind"},
+			{APIVersion: "extensions/v1beta1", Kind: "ReplicaSet", Name: "other-group"},
+		},
+	}}
+}
+
+func TestEnqueueOwner(t *testing.T) {
+	tests := []struct {
+		name         string
+		isController bool
+		expected     []string
+	}{
+		{name: "all owners", expected: []string{"default/controller", "default/owner"}},
+		{name: "controller owner", isController: true, expected: []string{"default/controller"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := yacht.EnqueueOwner[string](replicaSetGVK, tt.isController)(ownedPod())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keys, tt.expected) {
+				t.Fatalf("expected keys %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
+func TestEnqueueOwnerKeyTypes(t *testing.T) {
+	nnKeys, err := yacht.EnqueueOwner[types.NamespacedName](replicaSetGVK, true)(ownedPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []types.NamespacedName{{Namespace: "default", Name: "controller"}}; !reflect.DeepEqual(nnKeys, expected) {
+		t.Fatalf("expected keys %v, got %v", expected, nnKeys)
+	}
+
+	anyKeys, err := yacht.EnqueueOwner[interface{}](replicaSetGVK, true)(ownedPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []interface{}{"default/controller"}; !reflect.DeepEqual(anyKeys, expected) {
+		t.Fatalf("expected keys %v, got %v", expected, anyKeys)
+	}
+
+	if _, err = yacht.EnqueueOwner[int](replicaSetGVK, true)(ownedPod()); err == nil {
+		t.Fatal("expected an error for keys of unsupported type")
+	}
+	if _, err = yacht.EnqueueOwner[string](replicaSetGVK, true)("not an object"); err == nil {
+		t.Fatal("expected an error for non-object")
+	}
+}
+
+func TestMappedResourceEventHandlerFuncs(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	handler := h.Controller.MappedResourceEventHandlerFuncs(yacht.EnqueueMapped(func(obj interface{}) []string {
+		name := obj.(*corev1.Namespace).Name
+		return []string{name + "-1", name + "-2"}
+	}))
+	handler.OnAdd(namespace("a"), false)
+	handler.OnDelete(namespace("b"))
+	h.ProcessAll(context.Background())
+
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a-1", "a-2", "b-1", "b-2"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
+
+func TestEnqueueKeysFunc(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Controller.WithEnqueueKeysFunc(yacht.EnqueueOwner[string](replicaSetGVK, false))
+	h.Controller.Enqueue(ownedPod())
+	h.ProcessAll(context.Background())
+
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"default/controller", "default/owner"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
@@ -0,0 +1,143 @@
+package yacht
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorAction is the action to take on a failed work item
+type ErrorAction int
+
+const (
+	// ErrorActionRetryWithBackoff puts the work item back on the work queue with rate limiting
+	ErrorActionRetryWithBackoff ErrorAction = iota
+	// ErrorActionRetryFast puts the work item back on the work queue after a short delay, which starts from 5ms and
+	// doubles on each consecutive failure up to 1s
+	ErrorActionRetryFast
+	// ErrorActionDrop forgets the work item without retrying
+	ErrorActionDrop
+	// ErrorActionEscalate stops the controller and reports the error as a fatal error, which is returned from
+	// Manager.Start
+	ErrorActionEscalate
+)
+
+func (a ErrorAction) String() string {
+	switch a {
+	case ErrorActionRetryWithBackoff:
+		return "RetryWithBackoff"
+	case ErrorActionRetryFast:
+		return "RetryFast"
+	case ErrorActionDrop:
+		return "Drop"
+	case ErrorActionEscalate:
+		return "Escalate"
+	default:
+		return fmt.Sprintf("ErrorAction(%d)", int(a))
+	}
+}
+
+// ErrorPolicy classifies the errors returned from the handler into ErrorActions
+type ErrorPolicy func(err error) ErrorAction
+
+// DefaultErrorPolicy classifies the StatusErrors from the API server. Conflicts are retried fast, since they are
+// likely to succeed with the latest object. Requests that can never succeed, such as invalid, forbidden
+// and bad requests, are dropped. All the other errors are retried with backoff.
+func DefaultErrorPolicy(err error) ErrorAction {
+	switch {
+	case apierrors.IsConflict(err):
+		return ErrorActionRetryFast
+	case apierrors.IsInvalid(err),
+		apierrors.IsForbidden(err),
+		apierrors.IsBadRequest(err),
+		apierrors.IsMethodNotSupported(err),
+		apierrors.IsNotAcceptable(err),
+		apierrors.IsUnsupportedMediaType(err),
+		apierrors.IsRequestEntityTooLargeError(err):
+		return ErrorActionDrop
+	default:
+		return ErrorActionRetryWithBackoff
+	}
+}
+
+// WithErrorPolicy sets the policy to classify the errors returned from the handler. DefaultErrorPolicy is used
+// by default. TerminalErrors are always dropped, while reconcile timeouts and panics are always retried with
+// backoff regardless of the policy.
+func (c *TypedController[K]) WithErrorPolicy(policy ErrorPolicy) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate errorPolicy when controller %s is running", c.name))
+	}
+
+	if policy != nil {
+		c.errorPolicy = policy
+	}
+	return c
+}
+
+// classifyError returns the ErrorAction for err
+func (c *TypedController[K]) classifyError(err error) ErrorAction {
+	if IsTerminalError(err) {
+		return ErrorActionDrop
+	}
+	if IsReconcileTimeout(err) || IsPanicError(err) {
+		return ErrorActionRetryWithBackoff
+	}
+	return c.errorPolicy(err)
+}
+
+const (
+	fastRetryBaseDelay = 5 * time.Millisecond
+	fastRetryMaxDelay  = time.Second
+)
+
+// fastRetryDelay returns the delay of ErrorActionRetryFast after the given number of consecutive failures
+func fastRetryDelay(failures int) time.Duration {
+	delay := fastRetryBaseDelay
+	for i := 1; i < failures && delay < fastRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, fastRetryMaxDelay)
+}
+
+// failureTracker counts the consecutive failures of the work items. Unlike NumRequeues of the work queue, it also
+// counts the retries which are not rate limited by the work queue.
+type failureTracker[K comparable] struct {
+	lock     sync.Mutex
+	failures map[K]int
+}
+
+// inc records a failure of key and returns the number of consecutive failures
+func (t *failureTracker[K]) inc(key K) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.failures == nil {
+		t.failures = map[K]int{}
+	}
+	t.failures[key]++
+	return t.failures[key]
+}
+
+// get returns the number of consecutive failures of key
+func (t *failureTracker[K]) get(key K) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.failures[key]
+}
+
+// forget clears the failures of key
+func (t *failureTracker[K]) forget(key K) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.failures, key)
+}
+
+// escalate reports a fatal error, which stops the controller
+func (c *TypedController[K]) escalate(err error) {
+	select {
+	case c.fatalCh <- fmt.Errorf("controller %s stopped on escalated error: %w", c.name, err):
+	default:
+		// a fatal error has already been reported
+	}
+}
@@ -0,0 +1,90 @@
+package yacht_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestDefaultErrorPolicy(t *testing.T) {
+	resource := schema.GroupResource{Resource: "namespaces"}
+	tests := []struct {
+		err      error
+		expected yacht.ErrorAction
+	}{
+		{err: apierrors.NewConflict(resource, "a", errors.New("changed")), expected: yacht.ErrorActionRetryFast},
+		{err: apierrors.NewInvalid(schema.GroupKind{Kind: "Namespace"}, "a", nil), expected: yacht.ErrorActionDrop},
+		{err: apierrors.NewForbidden(resource, "a", errors.New("denied")), expected: yacht.ErrorActionDrop},
+		{err: apierrors.NewBadRequest("bad"), expected: yacht.ErrorActionDrop},
+		{err: apierrors.NewNotFound(resource, "a"), expected: yacht.ErrorActionRetryWithBackoff},
+		{err: apierrors.NewServiceUnavailable("unavailable"), expected: yacht.ErrorActionRetryWithBackoff},
+		{err: errors.New("failed"), expected: yacht.ErrorActionRetryWithBackoff},
+	}
+	for _, tt := range tests {
+		if action := yacht.DefaultErrorPolicy(tt.err); action != tt.expected {
+			t.Errorf("expected %s for %v, got %s", tt.expected, tt.err, action)
+		}
+	}
+}
+
+func TestErrorPolicy(t *testing.T) {
+	tests := []struct {
+		action      yacht.ErrorAction
+		err         error
+		rateLimited int
+		delayed     int
+	}{
+		{action: yacht.ErrorActionRetryWithBackoff, err: errors.New("failed"), rateLimited: 1},
+		{action: yacht.ErrorActionRetryFast, err: errors.New("failed"), delayed: 1},
+		{action: yacht.ErrorActionDrop, err: errors.New("failed")},
+		// terminal errors are always dropped
+		{action: yacht.ErrorActionRetryWithBackoff, err: yacht.TerminalError(errors.New("failed"))},
+	}
+	for _, tt := range tests {
+		h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+			return nil, tt.err
+		})
+		h.Controller.WithErrorPolicy(func(error) yacht.ErrorAction { return tt.action })
+		h.Add(namespace("a"))
+		h.ProcessAll(context.Background())
+		if rateLimited := len(h.Queue.RateLimited()); rateLimited != tt.rateLimited {
+			t.Errorf("expected %d rate limited work items for %s on %v, got %d", tt.rateLimited, tt.action,
+				tt.err, rateLimited)
+		}
+		if delayed := len(h.Queue.Delayed()); delayed != tt.delayed {
+			t.Errorf("expected %d delayed work items for %s on %v, got %d", tt.delayed, tt.action, tt.err, delayed)
+		}
+	}
+}
+
+func TestErrorPolicyEscalate(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		return nil, errors.New("failed")
+	})
+	h.Controller.WithErrorPolicy(func(error) yacht.ErrorAction { return yacht.ErrorActionEscalate })
+	manager := yacht.NewManager("test").WithControllers(h.Controller)
+	h.Add(namespace("a"))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- manager.Start(context.Background())
+	}()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("expected the escalated error to be returned")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the manager to stop on the escalated error")
+	}
+	if len(h.Queue.RateLimited()) != 0 {
+		t.Fatal("expected the escalated work item not to be retried")
+	}
+}
@@ -0,0 +1,101 @@
+package yacht
+
+import (
+	"fmt"
+	"maps"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// ResyncPolicy decides how the update events from periodic resyncs, where the ResourceVersion is unchanged, are
+// enqueued
+type ResyncPolicy int
+
+const (
+	// ResyncPolicyLowPriority enqueues the resyncs with PriorityResync, which only takes effect with a PriorityQueue
+	ResyncPolicyLowPriority ResyncPolicy = iota
+	// ResyncPolicyRateLimited enqueues the resyncs through EventHandlerOptions.ResyncRateLimiter
+	ResyncPolicyRateLimited
+	// ResyncPolicyIgnore ignores the resyncs
+	ResyncPolicyIgnore
+)
+
+// EventHandlerOptions configures which update events are enqueued by DefaultResourceEventHandlerFuncs and
+// MappedResourceEventHandlerFuncs. Add and Delete events are always enqueued, subject to the enqueueFilterFunc.
+type EventHandlerOptions struct {
+	// ResyncPolicy decides how the resyncs are enqueued. Resyncs are not subject to the change predicates below.
+	ResyncPolicy ResyncPolicy
+	// ResyncRateLimiter delays the resyncs with ResyncPolicyRateLimited. Defaults to 10 qps with a burst of 100.
+	ResyncRateLimiter workqueue.RateLimiter
+
+	// The change predicates. When any of them is set, an update is enqueued only if any of the chosen fields
+	// changes.
+
+	// GenerationChanged enqueues the updates changing metadata.generation, which skips the status-only updates
+	GenerationChanged bool
+	// LabelsChanged enqueues the updates changing the labels
+	LabelsChanged bool
+	// AnnotationsChanged enqueues the updates changing the annotations
+	AnnotationsChanged bool
+}
+
+// WithEventHandlerOptions sets the options to filter the update events in the default event handlers
+func (c *TypedController[K]) WithEventHandlerOptions(opts EventHandlerOptions) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate eventHandlerOptions when controller %s is running", c.name))
+	}
+
+	if opts.ResyncPolicy == ResyncPolicyRateLimited && opts.ResyncRateLimiter == nil {
+		opts.ResyncRateLimiter = &workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)}
+	}
+	c.eventHandlerOptions = opts
+	return c
+}
+
+// updateAddFunc returns how to add the keys of an update event onto the work queue. It returns false if the
+// update should be ignored.
+func (c *TypedController[K]) updateAddFunc(oldObj, newObj interface{}) (func(key K), bool) {
+	opts := c.eventHandlerOptions
+	if isResync(oldObj, newObj) {
+		switch opts.ResyncPolicy {
+		case ResyncPolicyIgnore:
+			return nil, false
+		case ResyncPolicyRateLimited:
+			return func(key K) {
+				c.queue.AddAfter(key, opts.ResyncRateLimiter.When(key))
+			}, true
+		default:
+			return c.addWithPriority(PriorityResync), true
+		}
+	}
+
+	if !opts.GenerationChanged && !opts.LabelsChanged && !opts.AnnotationsChanged {
+		return c.addWithPriority(PriorityDefault), true
+	}
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return c.addWithPriority(PriorityDefault), true
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return c.addWithPriority(PriorityDefault), true
+	}
+
+	switch {
+	case opts.GenerationChanged && oldMeta.GetGeneration() != newMeta.GetGeneration(),
+		opts.LabelsChanged && !maps.Equal(oldMeta.GetLabels(), newMeta.GetLabels()),
+		opts.AnnotationsChanged && !maps.Equal(oldMeta.GetAnnotations(), newMeta.GetAnnotations()):
+		return c.addWithPriority(PriorityDefault), true
+	default:
+		return nil, false
+	}
+}
+
+// addWithPriority returns a func to add the keys with priority
+func (c *TypedController[K]) addWithPriority(priority int) func(key K) {
+	return func(key K) {
+		c.queue.AddWithPriority(key, priority)
+	}
+}
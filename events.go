@@ -0,0 +1,128 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/dixudx/yacht/utils"
+)
+
+// Reasons of the events recorded for reconcile outcomes
+const (
+	ReasonReconciled     = "Reconciled"
+	ReasonRequeued       = "Requeued"
+	ReasonReconcileError = "ReconcileError"
+)
+
+// EventOptions configures which reconcile outcomes will be recorded as events
+type EventOptions struct {
+	// Error records a Warning event when the handler fails
+	Error bool
+	// Success records a Normal event when the handler succeeds. Use it with care, since an event is sent to the API
+	// server on every reconciliation, including the periodic resyncs.
+	Success bool
+	// Requeue records a Normal event when the handler asks to requeue the work item after a while
+	Requeue bool
+}
+
+// DefaultEventOptions only records events for failures, while the events for successes and requeues are opt-in
+var DefaultEventOptions = EventOptions{
+	Error: true,
+}
+
+type eventRecorderKey struct{}
+type involvedObjectKey struct{}
+
+// EventRecorderFromContext returns the EventRecorder of the controller inside the handler
+func EventRecorderFromContext(ctx context.Context) (record.EventRecorder, bool) {
+	recorder, ok := ctx.Value(eventRecorderKey{}).(record.EventRecorder)
+	return recorder, ok
+}
+
+// InvolvedObjectFromContext returns the last enqueued object of the work item inside the handler. For deletions, it
+// is the last known state of the deleted object.
+func InvolvedObjectFromContext(ctx context.Context) (runtime.Object, bool) {
+	obj, ok := ctx.Value(involvedObjectKey{}).(runtime.Object)
+	return obj, ok
+}
+
+// WithEventRecorder records Kubernetes events on the involved object for reconcile outcomes. The involved object
+// is the last enqueued object of the work item. When opts is nil, DefaultEventOptions will be used.
+// The recorder is also available inside the handler with EventRecorderFromContext.
+func (c *TypedController[K]) WithEventRecorder(recorder record.EventRecorder, opts *EventOptions) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate eventRecorder when controller %s is running", c.name))
+	}
+
+	if opts == nil {
+		opts = &DefaultEventOptions
+	}
+	c.recorder = recorder
+	c.eventOptions = *opts
+	return c
+}
+
+// rememberObject records obj as the involved object of key
+func (c *TypedController[K]) rememberObject(key K, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if o, ok := utils.InvolvedObject(obj); ok {
+		c.involvedObjects.Store(key, o)
+	}
+}
+
+// eventContext injects the recorder and the involved object of key into ctx
+func (c *TypedController[K]) eventContext(ctx context.Context, key K) (context.Context, runtime.Object) {
+	if c.recorder != nil {
+		ctx = context.WithValue(ctx, eventRecorderKey{}, c.recorder)
+	}
+	value, ok := c.involvedObjects.Load(key)
+	if !ok {
+		return ctx, nil
+	}
+	obj := value.(runtime.Object)
+	return context.WithValue(ctx, involvedObjectKey{}, obj), obj
+}
+
+// forgetObject removes the involved object of key, unless a newer one has been enqueued
+func (c *TypedController[K]) forgetObject(key K, obj runtime.Object) {
+	if obj != nil {
+		c.involvedObjects.CompareAndDelete(key, obj)
+	}
+}
+
+// recordEvent records an event on the involved object for the reconcile outcome
+func (c *TypedController[K]) recordEvent(obj runtime.Object, result Result, err error) {
+	if c.recorder == nil || obj == nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		if c.eventOptions.Error {
+			c.recorder.Eventf(obj, corev1.EventTypeWarning, ReasonReconcileError,
+				"Failed to reconcile by controller %s: %v", c.name, err)
+		}
+	case result.RequeueAfter > 0:
+		if c.eventOptions.Requeue {
+			c.recorder.Eventf(obj, corev1.EventTypeNormal, ReasonRequeued,
+				"Requeued after %v by controller %s", result.RequeueAfter, c.name)
+		}
+	case result.Requeue:
+		if c.eventOptions.Requeue {
+			c.recorder.Eventf(obj, corev1.EventTypeNormal, ReasonRequeued,
+				"Requeued by controller %s", c.name)
+		}
+	default:
+		if c.eventOptions.Success {
+			c.recorder.Eventf(obj, corev1.EventTypeNormal, ReasonReconciled,
+				"Successfully reconciled by controller %s", c.name)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package yacht
+
+// AliveWorkers returns the number of alive workers
+func (c *TypedController[K]) AliveWorkers() int {
+	c.workersLock.Lock()
+	defer c.workersLock.Unlock()
+	if c.pool == nil {
+		return 0
+	}
+	alive, _ := c.pool.workers()
+	return alive
+}
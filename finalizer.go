@@ -0,0 +1,117 @@
+package yacht
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// TypedFinalizer manages a finalizer on the objects processed by the controller. The finalizer is added on the
+// first reconciliation. Once the object is being deleted, CleanupFunc is called and the finalizer is removed
+// after the cleanup succeeds. Failures are retried through the rate limited work queue.
+type TypedFinalizer[K comparable] struct {
+	// Name is the name of the finalizer, e.g. example.com/cleanup
+	Name string
+	// GetFunc returns the latest object of the key, e.g. from a lister
+	GetFunc func(ctx context.Context, key K) (metav1.Object, error)
+	// PatchFunc applies a patch to the object, e.g. with a clientset or utils.NewDynamicPatchFunc
+	PatchFunc func(ctx context.Context, obj metav1.Object, patchType types.PatchType, data []byte) error
+	// CleanupFunc cleans up the external resources of the object being deleted
+	CleanupFunc func(ctx context.Context, obj metav1.Object) error
+}
+
+// Finalizer is the finalizer for Controller
+type Finalizer = TypedFinalizer[interface{}]
+
+// WithFinalizer sets a finalizer to clean up external resources before the objects are deleted
+func (c *TypedController[K]) WithFinalizer(finalizer TypedFinalizer[K]) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate finalizer when controller %s is running", c.name))
+	}
+	if len(finalizer.Name) == 0 || finalizer.GetFunc == nil || finalizer.PatchFunc == nil ||
+		finalizer.CleanupFunc == nil {
+		panic(fmt.Errorf("finalizer for controller %s should set Name, GetFunc, PatchFunc and CleanupFunc", c.name))
+	}
+
+	c.finalizer = &finalizer
+	return c
+}
+
+// reconcile processes the finalizer if any, then calls the handler
+func (c *TypedController[K]) reconcile(ctx context.Context, key K) (Result, error) {
+	if c.finalizer == nil {
+		return c.reconcileFunc(ctx, key)
+	}
+
+	obj, err := c.finalizer.GetFunc(ctx, key)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return c.reconcileFunc(ctx, key)
+		}
+		return Result{}, err
+	}
+
+	hasFinalizer := slices.Contains(obj.GetFinalizers(), c.finalizer.Name)
+	if obj.GetDeletionTimestamp().IsZero() {
+		if !hasFinalizer {
+			finalizers := append(append([]string{}, obj.GetFinalizers()...), c.finalizer.Name)
+			if err = c.patchFinalizers(ctx, obj, finalizers); err != nil {
+				return Result{}, err
+			}
+		}
+		return c.reconcileFunc(ctx, key)
+	}
+
+	if !hasFinalizer {
+		// cleanup has already been done
+		return Result{}, nil
+	}
+
+	if err = c.finalizer.CleanupFunc(ctx, obj); err != nil {
+		return Result{}, fmt.Errorf("failed to clean up %s/%s with finalizer %s: %w",
+			obj.GetNamespace(), obj.GetName(), c.finalizer.Name, err)
+	}
+	var finalizers []string
+	for _, f := range obj.GetFinalizers() {
+		if f != c.finalizer.Name {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if err = c.patchFinalizers(ctx, obj, finalizers); err != nil {
+		return Result{}, err
+	}
+	klog.V(4).Infof("removed finalizer %s from %s/%s for controller %s", c.finalizer.Name,
+		obj.GetNamespace(), obj.GetName(), c.name)
+	return Result{}, nil
+}
+
+// patchFinalizers replaces the finalizers of obj. The resourceVersion is carried in the patch, so that the patch
+// fails with a conflict if the object has been changed.
+func (c *TypedController[K]) patchFinalizers(ctx context.Context, obj metav1.Object, finalizers []string) error {
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	metadata := map[string]interface{}{
+		"finalizers": finalizers,
+	}
+	if rv := obj.GetResourceVersion(); len(rv) > 0 {
+		metadata["resourceVersion"] = rv
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.finalizer.PatchFunc(ctx, obj, types.MergePatchType, data); err != nil {
+		return fmt.Errorf("failed to patch finalizers of %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
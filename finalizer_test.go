@@ -0,0 +1,160 @@
+package yacht_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+const finalizerName = "example.com/cleanup"
+
+// newFinalizerHarness creates a Harness with a finalizer managing the seeded namespaces. It records the patches
+// and the cleaned up namespaces.
+func newFinalizerHarness(cleanupErr error, objects ...*corev1.Namespace) (*yachttest.Harness[string], *[]string, *[]string) {
+	var seeded []runtime.Object
+	for _, obj := range objects {
+		seeded = append(seeded, obj)
+	}
+	h := yachttest.NewHarness[string]("test", succeed, seeded...)
+	var patches, cleanups []string
+	h.Controller.WithFinalizer(yacht.TypedFinalizer[string]{
+		Name: finalizerName,
+		GetFunc: func(ctx context.Context, key string) (metav1.Object, error) {
+			return h.Client.CoreV1().Namespaces().Get(ctx, key, metav1.GetOptions{})
+		},
+		PatchFunc: func(ctx context.Context, obj metav1.Object, patchType types.PatchType, data []byte) error {
+			patches = append(patches, string(data))
+			_, err := h.Client.CoreV1().Namespaces().Patch(ctx, obj.GetName(), patchType, data, metav1.PatchOptions{})
+			return err
+		},
+		CleanupFunc: func(_ context.Context, obj metav1.Object) error {
+			cleanups = append(cleanups, obj.GetName())
+			return cleanupErr
+		},
+	})
+	return h, &patches, &cleanups
+}
+
+// finalizers returns the finalizers of the namespace in the fake clientset
+func finalizers(t *testing.T, h *yachttest.Harness[string], name string) []string {
+	t.Helper()
+	ns, err := h.Client.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ns.Finalizers
+}
+
+func deletingNamespace(name string, finalizers ...string) *corev1.Namespace {
+	ns := namespace(name)
+	ns.ResourceVersion = "1"
+	ns.Finalizers = finalizers
+	now := metav1.Now()
+	ns.DeletionTimestamp = &now
+	return ns
+}
+
+func TestFinalizerAdded(t *testing.T) {
+	ns := namespace("a")
+	ns.ResourceVersion = "1"
+	ns.Finalizers = []string{"other"}
+	h, patches, cleanups := newFinalizerHarness(nil, ns)
+	h.Add(ns)
+	h.ProcessAll(context.Background())
+
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+	if len(*cleanups) != 0 {
+		t.Fatalf("unexpected cleanups %v", *cleanups)
+	}
+	var patch map[string]map[string]interface{}
+	if len(*patches) != 1 || json.Unmarshal([]byte((*patches)[0]), &patch) != nil {
+		t.Fatalf("unexpected patches %v", *patches)
+	}
+	// the resourceVersion makes the patch fail on conflicts
+	if rv := patch["metadata"]["resourceVersion"]; rv != "1" {
+		t.Fatalf("expected the resourceVersion in the patch, got %v", rv)
+	}
+	if got := finalizers(t, h, "a"); !reflect.DeepEqual(got, []string{"other", finalizerName}) {
+		t.Fatalf("unexpected finalizers %v", got)
+	}
+
+	// the finalizer is only added once
+	h.Add(ns)
+	h.ProcessAll(context.Background())
+	if len(*patches) != 1 {
+		t.Fatalf("unexpected patches %v", *patches)
+	}
+}
+
+func TestFinalizerRemovedAfterCleanup(t *testing.T) {
+	ns := deletingNamespace("a", "other", finalizerName)
+	h, patches, cleanups := newFinalizerHarness(nil, ns)
+	h.Add(ns)
+	h.ProcessAll(context.Background())
+
+	if !reflect.DeepEqual(*cleanups, []string{"a"}) {
+		t.Fatalf("unexpected cleanups %v", *cleanups)
+	}
+	if len(*patches) != 1 {
+		t.Fatalf("unexpected patches %v", *patches)
+	}
+	if got := finalizers(t, h, "a"); !reflect.DeepEqual(got, []string{"other"}) {
+		t.Fatalf("unexpected finalizers %v", got)
+	}
+	// the handler is not called for the objects being deleted
+	if calls := h.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	if rateLimited := h.Queue.RateLimited(); len(rateLimited) != 0 {
+		t.Fatalf("unexpected retries %v", rateLimited)
+	}
+}
+
+func TestFinalizerCleanupFailure(t *testing.T) {
+	ns := deletingNamespace("a", finalizerName)
+	h, patches, cleanups := newFinalizerHarness(errors.New("failed"), ns)
+	h.Add(ns)
+	h.ProcessAll(context.Background())
+
+	if !reflect.DeepEqual(*cleanups, []string{"a"}) {
+		t.Fatalf("unexpected cleanups %v", *cleanups)
+	}
+	if len(*patches) != 0 {
+		t.Fatalf("unexpected patches %v", *patches)
+	}
+	if got := finalizers(t, h, "a"); !reflect.DeepEqual(got, []string{finalizerName}) {
+		t.Fatalf("unexpected finalizers %v", got)
+	}
+	if rateLimited := h.Queue.RateLimited(); len(rateLimited) != 1 {
+		t.Fatalf("expected the work item to be retried, got %v", rateLimited)
+	}
+}
+
+func TestFinalizerSkipsCleanedUpObjects(t *testing.T) {
+	ns := deletingNamespace("a", "other")
+	h, patches, cleanups := newFinalizerHarness(nil, ns)
+	h.Add(ns)
+	// not found
+	h.Add(namespace("b"))
+	h.ProcessAll(context.Background())
+
+	if len(*cleanups) != 0 || len(*patches) != 0 {
+		t.Fatalf("unexpected cleanups %v and patches %v", *cleanups, *patches)
+	}
+	// the handler is still called for the object not found
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
@@ -0,0 +1,131 @@
+package yacht
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// HealthzPath is the path to serve the liveness probe
+	HealthzPath = "/healthz"
+	// ReadyzPath is the path to serve the readiness probe
+	ReadyzPath = "/readyz"
+	// LeaderzPath is the path to report whether the lease is held, which always passes without leader election
+	LeaderzPath = "/leaderz"
+)
+
+// Healthy returns nil if the controller is alive. A controller which has not started workers yet, e.g. waiting for
+// the lease, or is shutting down is considered healthy. Otherwise, all the launched workers should be alive.
+func (c *TypedController[K]) Healthy() error {
+	if c.shuttingDown.Load() {
+		return nil
+	}
+
+	c.workersLock.Lock()
+	pool := c.pool
+	c.workersLock.Unlock()
+	if pool == nil {
+		return nil
+	}
+	if alive, workers := pool.workers(); alive < workers {
+		return fmt.Errorf("controller %s has %d/%d workers alive", c.name, alive, workers)
+	}
+	return nil
+}
+
+// Ready returns nil if the controller is ready to process work items, which means all the informersSynced have
+// completed and the workers are alive and not paused. With leader election, a controller waiting for the lease is
+// considered ready, so that standby replicas do not block rolling updates. Use IsLeader or LeaderzPath to check
+// whether the lease is held.
+func (c *TypedController[K]) Ready() error {
+	if c.le != nil && !c.IsLeader() {
+		return nil
+	}
+	if !c.cacheSynced.Load() {
+		return fmt.Errorf("controller %s is waiting for caches to sync", c.name)
+	}
+	if !c.workersStarted.Load() {
+		return fmt.Errorf("controller %s has not started workers", c.name)
+	}
+	if c.Paused() {
+		return fmt.Errorf("controller %s is paused", c.name)
+	}
+	return c.Healthy()
+}
+
+// leader returns nil if the controller holds the lease or runs without leader election
+func (c *TypedController[K]) leader() error {
+	if c.le != nil && !c.IsLeader() {
+		return fmt.Errorf("controller %s is not the leader", c.name)
+	}
+	return nil
+}
+
+// HealthProbeHandler returns an http.Handler serving HealthzPath, ReadyzPath and LeaderzPath for the controller
+func (c *TypedController[K]) HealthProbeHandler() http.Handler {
+	return newHealthProbeHandler(c.Healthy, c.Ready, c.leader)
+}
+
+// Healthy returns nil if all the registered controllers are alive
+func (m *Manager) Healthy() error {
+	var errs []error
+	for _, c := range m.controllers {
+		errs = append(errs, c.Healthy())
+	}
+	return errors.Join(errs...)
+}
+
+// Ready returns nil if all the registered controllers are ready to process work items. With leader election, a
+// manager waiting for the lease is considered ready, like TypedController.Ready.
+func (m *Manager) Ready() error {
+	if m.le != nil && !m.IsLeader() {
+		return nil
+	}
+
+	var errs []error
+	for _, c := range m.controllers {
+		errs = append(errs, c.Ready())
+	}
+	return errors.Join(errs...)
+}
+
+// IsLeader checks whether the manager holds the lease. It is always false without leader election.
+func (m *Manager) IsLeader() bool {
+	return m.le != nil && m.leading.Load() && m.le.IsLeader()
+}
+
+// leader returns nil if the manager holds the lease or runs without leader election
+func (m *Manager) leader() error {
+	if m.le != nil && !m.IsLeader() {
+		return fmt.Errorf("manager %s is not the leader", m.name)
+	}
+	return nil
+}
+
+// HealthProbeHandler returns an http.Handler serving HealthzPath, ReadyzPath and LeaderzPath for all the registered
+// controllers
+func (m *Manager) HealthProbeHandler() http.Handler {
+	return newHealthProbeHandler(m.Healthy, m.Ready, m.leader)
+}
+
+func newHealthProbeHandler(healthy, ready, leader func() error) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(HealthzPath, checkHandler(healthy))
+	mux.Handle(ReadyzPath, checkHandler(ready))
+	mux.Handle(LeaderzPath, checkHandler(leader))
+	return mux
+}
+
+// checkHandler responds 200 if the check passes, otherwise 503 with the error message
+func checkHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}
+}
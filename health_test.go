@@ -0,0 +1,102 @@
+package yacht_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+// probe returns the status code of path served by handler
+func probe(handler http.Handler, path string) int {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestHealthyWhileStartingWorkers(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		h := yachttest.NewHarness[string]("test", succeed)
+		h.Controller.WithWorkers(64)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			h.Controller.Run(ctx)
+		}()
+		for h.Controller.Ready() != nil {
+			if err := h.Controller.Healthy(); err != nil {
+				t.Fatalf("unexpected error while starting workers: %v", err)
+			}
+		}
+
+		h.Controller.SetWorkers(128)
+		if err := h.Controller.Healthy(); err != nil {
+			t.Fatalf("unexpected error after adding workers: %v", err)
+		}
+		cancel()
+		<-done
+	}
+}
+
+func TestHealthProbeHandler(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	handler := h.Controller.HealthProbeHandler()
+	if code := probe(handler, yacht.HealthzPath); code != http.StatusOK {
+		t.Fatalf("expected a controller not started to be healthy, got %d", code)
+	}
+	if code := probe(handler, yacht.ReadyzPath); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a controller not started to be unready, got %d", code)
+	}
+	if code := probe(handler, yacht.LeaderzPath); code != http.StatusOK {
+		t.Fatalf("expected a controller without leader election to pass the leader check, got %d", code)
+	}
+
+	stop := run(t, h)
+	defer stop()
+	if code := probe(handler, yacht.ReadyzPath); code != http.StatusOK {
+		t.Fatalf("expected a running controller to be ready, got %d", code)
+	}
+	h.Controller.Pause()
+	if code := probe(handler, yacht.ReadyzPath); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a paused controller to be unready, got %d", code)
+	}
+	if code := probe(handler, yacht.HealthzPath); code != http.StatusOK {
+		t.Fatalf("expected a paused controller to be healthy, got %d", code)
+	}
+}
+
+func TestStandbyIsReady(t *testing.T) {
+	h := newRestartableHarness(succeed)
+	holdLease(t, h.Client, "other", time.Minute)
+	h.Controller.WithLeaderElection(leaseLock(h.Client, "test"), 10*time.Second, 5*time.Second, time.Second)
+	manager := yacht.NewManager("test").
+		WithLeaderElection(leaseLock(h.Client, "test"), 10*time.Second, 5*time.Second, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.Controller.Run(ctx)
+	go func() {
+		_ = manager.Start(ctx)
+	}()
+	eventually(t, "the leader to be observed", func() bool { return h.Controller.CurrentLeader() == "other" })
+
+	for name, handler := range map[string]http.Handler{
+		"controller": h.Controller.HealthProbeHandler(),
+		"manager":    manager.HealthProbeHandler(),
+	} {
+		if code := probe(handler, yacht.ReadyzPath); code != http.StatusOK {
+			t.Errorf("expected the standby %s to be ready, got %d", name, code)
+		}
+		if code := probe(handler, yacht.LeaderzPath); code != http.StatusServiceUnavailable {
+			t.Errorf("expected the standby %s to fail the leader check, got %d", name, code)
+		}
+	}
+	if h.Controller.IsLeader() || manager.IsLeader() {
+		t.Fatal("expected the standby not to be the leader")
+	}
+}
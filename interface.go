@@ -2,8 +2,10 @@ package yacht
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
 )
@@ -14,15 +16,96 @@ type Interface interface {
 	// Deprecated: Use WithHandlerContextFunc instead.
 	WithHandlerFunc(HandlerFunc) *Controller
 	WithHandlerContextFunc(HandlerContextFunc) *Controller
+	WithReconcileFunc(ReconcileFunc) *Controller
 	WithLeaderElection(leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration) *Controller
 	WithCacheSynced(...cache.InformerSynced) *Controller
 }
 
+// TypedInterface is the typed version of Interface
+type TypedInterface[K comparable] interface {
+	Enqueue(obj interface{})
+	WithEnqueueFunc(TypedEnqueueFunc[interface{}, K]) *TypedController[K]
+	WithHandlerContextFunc(TypedHandlerFunc[K]) *TypedController[K]
+	WithReconcileFunc(TypedReconcileFunc[K]) *TypedController[K]
+	WithLeaderElection(leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration) *TypedController[K]
+	WithCacheSynced(...cache.InformerSynced) *TypedController[K]
+}
+
 // Deprecated: Use HandlerContextFunc instead.
 type HandlerFunc func(key interface{}) (requeueAfter *time.Duration, err error)
 
-type HandlerContextFunc func(ctx context.Context, key interface{}) (requeueAfter *time.Duration, err error)
+type HandlerContextFunc = TypedHandlerFunc[interface{}]
+
+type EnqueueFunc = TypedEnqueueFunc[interface{}, interface{}]
+
+type EnqueueFilterFunc = TypedFilterFunc[interface{}]
+
+// TypedHandlerFunc processes the work item with a key of type K
+type TypedHandlerFunc[K comparable] func(ctx context.Context, key K) (requeueAfter *time.Duration, err error)
+
+// TypedEnqueueFunc converts an object of type T into a key of type K
+type TypedEnqueueFunc[T any, K comparable] func(obj T) (K, error)
+
+// TypedEnqueueKeysFunc converts an object of type T into multiple keys of type K
+type TypedEnqueueKeysFunc[T any, K comparable] func(obj T) ([]K, error)
 
-type EnqueueFunc func(obj interface{}) (interface{}, error)
+type EnqueueKeysFunc = TypedEnqueueKeysFunc[interface{}, interface{}]
 
-type EnqueueFilterFunc func(oldObj, newObj interface{}) (bool, error)
+// TypedFilterFunc filters objects of type T before enqueueing. oldObj is the zero value on additions, and newObj
+// is the zero value on deletions.
+type TypedFilterFunc[T any] func(oldObj, newObj T) (bool, error)
+
+// EnqueueFuncFor converts a TypedEnqueueFunc working on objects of type T, which can be set with WithEnqueueFunc.
+// An error is returned instead of panicking when the object is not a T.
+func EnqueueFuncFor[T any, K comparable](enqueueFunc TypedEnqueueFunc[T, K]) TypedEnqueueFunc[interface{}, K] {
+	return func(obj interface{}) (K, error) {
+		t, err := convertObject[T](obj)
+		if err != nil {
+			var zero K
+			return zero, err
+		}
+		return enqueueFunc(t)
+	}
+}
+
+// FilterFuncFor converts a TypedFilterFunc working on objects of type T, which can be set with
+// WithEnqueueFilterFunc. An error is returned instead of panicking when the objects are not of type T.
+func FilterFuncFor[T any](filterFunc TypedFilterFunc[T]) EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		oldT, err := convertObject[T](oldObj)
+		if err != nil {
+			return false, err
+		}
+		newT, err := convertObject[T](newObj)
+		if err != nil {
+			return false, err
+		}
+		return filterFunc(oldT, newT)
+	}
+}
+
+// NamespacedNameEnqueueFunc converts an object into a types.NamespacedName key
+func NamespacedNameEnqueueFunc(obj interface{}) (types.NamespacedName, error) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// convertObject converts obj into type T. A nil obj is converted into the zero value of T.
+func convertObject[T any](obj interface{}) (T, error) {
+	var zero T
+	if obj == nil {
+		return zero, nil
+	}
+	t, ok := obj.(T)
+	if !ok {
+		return zero, fmt.Errorf("expected object of type %T, but got %T", zero, obj)
+	}
+	return t, nil
+}
@@ -0,0 +1,96 @@
+package yacht
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// KeyLock is a registry of keys being processed, which can be shared by multiple controllers, so that the same
+// key, e.g. <namespace>/<name>, is processed by at most one handler at a time across these controllers.
+type KeyLock struct {
+	// RetryDelay is the delay to requeue a work item whose key is held by another handler
+	RetryDelay time.Duration
+
+	lock sync.Mutex
+	keys map[string]string
+}
+
+// NewKeyLock creates a new KeyLock, which requeues the work items of busy keys after 100ms
+func NewKeyLock() *KeyLock {
+	return &KeyLock{
+		RetryDelay: 100 * time.Millisecond,
+		keys:       map[string]string{},
+	}
+}
+
+// TryLock acquires key for holder without blocking. It returns false if key is held by others.
+func (l *KeyLock) TryLock(key, holder string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.keys[key]; ok {
+		return false
+	}
+	l.keys[key] = holder
+	return true
+}
+
+// Unlock releases key
+func (l *KeyLock) Unlock(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.keys, key)
+}
+
+// Holder returns who is holding key
+func (l *KeyLock) Holder(key string) (string, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	holder, ok := l.keys[key]
+	return holder, ok
+}
+
+// WithKeyLock joins the shared KeyLock. A work item whose key is being processed by another controller sharing
+// keyLock is requeued after keyLock.RetryDelay, instead of blocking the worker.
+func (c *TypedController[K]) WithKeyLock(keyLock *KeyLock) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate keyLock when controller %s is running", c.name))
+	}
+
+	c.keyLock = keyLock
+	return c
+}
+
+// lockKey acquires the key of item from the shared KeyLock. It returns false with the work item requeued if the
+// key is busy, otherwise a func to release the key.
+func (c *TypedController[K]) lockKey(item K) (func(), bool) {
+	if c.keyLock == nil {
+		return func() {}, true
+	}
+
+	key := lockKeyString(item)
+	if !c.keyLock.TryLock(key, c.name) {
+		holder, _ := c.keyLock.Holder(key)
+		klog.V(4).Infof("controller %s requeues work item %v held by controller %s", c.name, item, holder)
+		c.queue.AddAfter(item, c.keyLock.RetryDelay)
+		return nil, false
+	}
+	return func() { c.keyLock.Unlock(key) }, true
+}
+
+// lockKeyString converts item into a string key, so that work items of different types can share a KeyLock
+func lockKeyString(item interface{}) string {
+	switch key := item.(type) {
+	case string:
+		return key
+	case types.NamespacedName:
+		return keyString(key)
+	case fmt.Stringer:
+		return key.String()
+	default:
+		return fmt.Sprint(key)
+	}
+}
@@ -0,0 +1,66 @@
+package yacht_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestKeyLock(t *testing.T) {
+	keyLock := yacht.NewKeyLock()
+	if !keyLock.TryLock("a", "foo") {
+		t.Fatal("expected to acquire a free key")
+	}
+	if keyLock.TryLock("a", "bar") {
+		t.Fatal("expected not to acquire a key held by others")
+	}
+	if holder, ok := keyLock.Holder("a"); !ok || holder != "foo" {
+		t.Fatalf("expected key held by foo, got %q", holder)
+	}
+	keyLock.Unlock("a")
+	if _, ok := keyLock.Holder("a"); ok {
+		t.Fatal("expected key to be released")
+	}
+	if !keyLock.TryLock("a", "bar") {
+		t.Fatal("expected to acquire a released key")
+	}
+}
+
+func TestKeyLockSharedByControllers(t *testing.T) {
+	keyLock := yacht.NewKeyLock()
+	keyLock.RetryDelay = time.Second
+	nn := yachttest.NewHarness[types.NamespacedName]("bar",
+		func(_ context.Context, _ types.NamespacedName) (*time.Duration, error) {
+			return nil, nil
+		})
+	nn.Controller.WithEnqueueFunc(yacht.NamespacedNameEnqueueFunc).WithKeyLock(keyLock)
+
+	var busy bool
+	h := yachttest.NewHarness[string]("foo", func(ctx context.Context, _ string) (*time.Duration, error) {
+		// the controllers key work items differently, but the key of namespace "a" is the same
+		busy = nn.ProcessNext(ctx) && len(nn.CalledKeys()) == 0
+		return nil, nil
+	})
+	h.Controller.WithKeyLock(keyLock)
+
+	nn.Add(namespace("a"))
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+	if !busy {
+		t.Fatal("expected the key to be busy while processed by the other controller")
+	}
+	if delayed := nn.Queue.Delayed(); len(delayed) != 1 || delayed[0].Duration != time.Second {
+		t.Fatalf("expected the busy work item to be requeued after RetryDelay, got %v", delayed)
+	}
+
+	nn.Flush()
+	nn.ProcessAll(context.Background())
+	if keys := nn.CalledKeys(); len(keys) != 1 || keys[0] != (types.NamespacedName{Name: "a"}) {
+		t.Fatalf("expected the work item to be processed once the key is released, got %v", keys)
+	}
+}
@@ -0,0 +1,166 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/dixudx/yacht/metrics"
+)
+
+// LeaderHooks are called on the leadership transitions of a controller with leader election. All of them are
+// optional, and should return quickly.
+type LeaderHooks struct {
+	// OnStartedLeading is called once the lease is acquired, before the workers start
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading is called once the lease is lost or released
+	OnStoppedLeading func()
+	// OnNewLeader is called when a new leader is observed, which may be the controller itself
+	OnNewLeader func(identity string)
+}
+
+// WithLeaderHooks registers the hooks on the leadership transitions. Multiple hooks are called in the order of
+// registration.
+func (c *TypedController[K]) WithLeaderHooks(hooks LeaderHooks) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate leaderHooks when controller %s is running", c.name))
+	}
+
+	c.leaderHooks = append(c.leaderHooks, hooks)
+	return c
+}
+
+// IsLeader checks whether the controller holds the lease. It is always false without leader election.
+func (c *TypedController[K]) IsLeader() bool {
+	// the LeaderElector keeps the last observed record after failing to renew the lease
+	return c.le != nil && c.leading.Load() && c.le.IsLeader()
+}
+
+// CurrentLeader returns the identity of the last observed leader, which is empty without leader election or
+// before any leader is observed
+func (c *TypedController[K]) CurrentLeader() string {
+	if c.le == nil {
+		return ""
+	}
+	return c.le.GetLeader()
+}
+
+// startedLeading records the acquisition of the lease
+func (c *TypedController[K]) startedLeading(ctx context.Context) {
+	c.leading.Store(true)
+	c.metricsProvider.NewLeaderMetric(c.name).Set(1)
+	c.metricsProvider.NewLeaderTransitionsMetric(c.name, metrics.TransitionStartedLeading).Inc()
+	for _, hooks := range c.leaderHooks {
+		if hooks.OnStartedLeading != nil {
+			hooks.OnStartedLeading(ctx)
+		}
+	}
+}
+
+// stoppedLeading records the loss or release of the lease. The LeaderElector notifies it even if the lease has
+// never been acquired, which is ignored.
+func (c *TypedController[K]) stoppedLeading() {
+	if !c.leading.Swap(false) {
+		return
+	}
+	c.metricsProvider.NewLeaderMetric(c.name).Set(0)
+	c.metricsProvider.NewLeaderTransitionsMetric(c.name, metrics.TransitionStoppedLeading).Inc()
+	for _, hooks := range c.leaderHooks {
+		if hooks.OnStoppedLeading != nil {
+			hooks.OnStoppedLeading()
+		}
+	}
+}
+
+// newLeader records the change of the leader
+func (c *TypedController[K]) newLeader(identity string) {
+	c.metricsProvider.NewLeaderTransitionsMetric(c.name, metrics.TransitionNewLeader).Inc()
+	for _, hooks := range c.leaderHooks {
+		if hooks.OnNewLeader != nil {
+			hooks.OnNewLeader(identity)
+		}
+	}
+}
+
+// WithExitOnLeaseLoss exits the process once the lease is lost and all the workers have stopped, instead of
+// waiting to be re-elected. This suits the controllers which rely on a restart to rebuild their states. When running
+// in a Manager with leader election, the process exits once all the controllers of the manager have stopped.
+func (c *TypedController[K]) WithExitOnLeaseLoss() *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate exitOnLeaseLoss when controller %s is running", c.name))
+	}
+
+	c.exitOnLeaseLoss = true
+	return c
+}
+
+// leaseLost checks whether the run is stopped due to the loss of the lease, rather than being asked to stop
+func (c *TypedController[K]) leaseLost(leaseLost <-chan struct{}) bool {
+	select {
+	case <-c.stopCh:
+		return false
+	default:
+	}
+	select {
+	case <-leaseLost:
+		return true
+	default:
+		return false
+	}
+}
+
+// onLeaseLost is called once the workers of a lost term have stopped
+func (c *TypedController[K]) onLeaseLost() {
+	if c.exitOnLeaseLoss {
+		klog.Errorf("controller %s lost the lease, exiting", c.name)
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+	}
+	klog.Errorf("controller %s lost the lease, waiting to be re-elected", c.name)
+}
+
+// enqueueAll enqueues all the known keys with PriorityResync, and the keys not processed yet with PriorityDefault,
+// since the events are dropped while the controller is not running
+func (c *TypedController[K]) enqueueAll() {
+	c.involvedObjects.Range(func(key, _ interface{}) bool {
+		c.markEnqueued(key.(K))
+		c.queue.AddWithPriority(key.(K), PriorityDefault)
+		return true
+	})
+	for _, key := range c.knownKeys.list() {
+		c.markEnqueued(key)
+		c.queue.AddWithPriority(key, PriorityResync)
+	}
+}
+
+// keySet is a set of keys safe for concurrent use
+type keySet[K comparable] struct {
+	lock sync.Mutex
+	keys map[K]struct{}
+}
+
+func (s *keySet[K]) insert(key K) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.keys == nil {
+		s.keys = map[K]struct{}{}
+	}
+	s.keys[key] = struct{}{}
+}
+
+func (s *keySet[K]) delete(key K) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.keys, key)
+}
+
+func (s *keySet[K]) list() []K {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys := make([]K, 0, len(s.keys))
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,179 @@
+package yacht_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/metrics"
+)
+
+func TestManagerCancelsWorkersOnLeaseLoss(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan time.Time, 1)
+	h := newRestartableHarness(func(ctx context.Context, _ string) (*time.Duration, error) {
+		close(started)
+		<-ctx.Done()
+		cancelled <- time.Now()
+		return nil, nil
+	})
+	// the in-flight work item would have been drained for a minute if the lease were released on purpose
+	h.Controller.WithDrainTimeout(time.Minute)
+	blocked := blockLeaseUpdates(h.Client)
+	manager := yacht.NewManager("test").WithControllers(h.Controller).
+		WithLeaderElection(leaseLock(h.Client, "test"), time.Second, 500*time.Millisecond, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- manager.Start(ctx)
+	}()
+	eventually(t, "the manager to lead", manager.IsLeader)
+	h.Add(namespace("a"))
+	<-started
+
+	blocked.Store(true)
+	lost := time.Now()
+	select {
+	case at := <-cancelled:
+		// the lease is lost after the renew deadline
+		if elapsed := at.Sub(lost); elapsed > 5*time.Second {
+			t.Fatalf("took %v to cancel the handler after the lease is lost", elapsed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the handler to be cancelled once the lease is lost")
+	}
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("expected an error once the lease is lost")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the manager to return once the lease is lost")
+	}
+}
+
+func TestReElectionEnqueuesKnownKeys(t *testing.T) {
+	h := newRestartableHarness(succeed)
+	blocked := blockLeaseUpdates(h.Client)
+	h.Controller.WithLeaderElection(leaseLock(h.Client, "test"), time.Second, 500*time.Millisecond,
+		100*time.Millisecond)
+	defer runUntilCancelled(h)()
+	eventually(t, "the controller to lead", h.Controller.IsLeader)
+
+	// enqueued without the default event handlers, e.g. by a custom event handler
+	h.Controller.Enqueue(namespace("a"))
+	eventually(t, "the work item to be processed", func() bool { return len(h.Calls()) == 1 })
+
+	blocked.Store(true)
+	eventually(t, "the lease to be lost", func() bool { return !h.Controller.IsLeader() })
+	// dropped while not leading
+	h.Controller.Enqueue(namespace("b"))
+	h.Reset()
+	blocked.Store(false)
+
+	eventually(t, "the work items to be processed after re-election", func() bool { return len(h.Calls()) == 2 })
+	keys := map[string]bool{}
+	for _, key := range h.CalledKeys() {
+		keys[key] = true
+	}
+	if !keys["a"] || !keys["b"] {
+		t.Fatalf("unexpected called keys %v after re-election", h.CalledKeys())
+	}
+}
+
+// transitions records the leadership transitions observed by the hooks
+type transitions struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (tr *transitions) hooks(name string) yacht.LeaderHooks {
+	return yacht.LeaderHooks{
+		OnStartedLeading: func(_ context.Context) { tr.record(name + " started leading") },
+		OnStoppedLeading: func() { tr.record(name + " stopped leading") },
+		OnNewLeader:      func(identity string) { tr.record(name + " observed " + identity) },
+	}
+}
+
+func (tr *transitions) record(event string) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	tr.events = append(tr.events, event)
+}
+
+func (tr *transitions) list() []string {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+	return append([]string{}, tr.events...)
+}
+
+func TestLeaderHooks(t *testing.T) {
+	registry := metrics.NewRegistry()
+	tr := &transitions{}
+	h := newRestartableHarness(succeed)
+	blocked := blockLeaseUpdates(h.Client)
+	h.Controller.WithMetricsProvider(registry).
+		WithLeaderHooks(tr.hooks("first")).
+		WithLeaderHooks(tr.hooks("second")).
+		WithLeaderElection(leaseLock(h.Client, "test"), time.Second, 500*time.Millisecond, 100*time.Millisecond)
+	output := func() string {
+		var sb strings.Builder
+		if _, err := registry.WriteTo(&sb); err != nil {
+			t.Fatal(err)
+		}
+		return sb.String()
+	}
+	defer runUntilCancelled(h)()
+
+	eventually(t, "the controller to lead", h.Controller.IsLeader)
+	eventually(t, "the new leader to be observed", func() bool { return len(tr.list()) == 4 })
+	if leader := h.Controller.CurrentLeader(); leader != "test" {
+		t.Fatalf("expected the controller to be the current leader, got %q", leader)
+	}
+	events := tr.list()
+	// the new leader is observed concurrently with the start of leading
+	sort.Strings(events)
+	expected := []string{"first observed test", "first started leading", "second observed test", "second started leading"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+
+	blocked.Store(true)
+	eventually(t, "the lease to be lost", func() bool { return len(tr.list()) == 6 })
+	if events = tr.list()[4:]; !reflect.DeepEqual(events, []string{"first stopped leading", "second stopped leading"}) {
+		t.Fatalf("expected the hooks to be called in order on the loss of the lease, got %v", events)
+	}
+	for _, line := range []string{
+		`yacht_leader{controller="test"} 0`,
+		`yacht_leader_transitions_total{controller="test",transition="started_leading"} 1`,
+		`yacht_leader_transitions_total{controller="test",transition="stopped_leading"} 1`,
+		`yacht_leader_transitions_total{controller="test",transition="new_leader"} 1`,
+	} {
+		if !strings.Contains(output(), line) {
+			t.Errorf("expected %q in output:\n%s", line, output())
+		}
+	}
+}
+
+func TestLeaderHooksOnStandby(t *testing.T) {
+	tr := &transitions{}
+	h := newRestartableHarness(succeed)
+	holdLease(t, h.Client, "other", time.Minute)
+	h.Controller.WithLeaderHooks(tr.hooks("standby")).
+		WithLeaderElection(leaseLock(h.Client, "test"), 10*time.Second, 5*time.Second, time.Second)
+
+	stop := runUntilCancelled(h)
+	eventually(t, "the leader to be observed", func() bool { return h.Controller.CurrentLeader() == "other" })
+	stop()
+	// the LeaderElector notifies the stop of leading even if the lease has never been acquired
+	if events := tr.list(); !reflect.DeepEqual(events, []string{"standby observed other"}) {
+		t.Fatalf("expected only the new leader to be observed, got %v", events)
+	}
+}
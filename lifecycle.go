@@ -0,0 +1,114 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// WithQueueFunc works like WithQueue, while a new queue is created by newQueue each time the controller is
+// restarted with Run after being stopped, or starts a new leader term.
+func (c *TypedController[K]) WithQueueFunc(newQueue func() workqueue.RateLimitingInterface) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate queue when controller %s is running", c.name))
+	}
+
+	newTypedQueue := func() TypedRateLimitingInterface[K] {
+		return NewTypedRateLimitingQueue[K](newQueue())
+	}
+	c.queue = newRestartableQueue(newTypedQueue(), newTypedQueue)
+	return c
+}
+
+// start prepares a new run of the controller. It returns false if the controller is already running or the
+// queue can not be rebuilt.
+func (c *TypedController[K]) start(ctx context.Context) (context.Context, bool) {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.cancelRun != nil {
+		klog.Warningf("controller %s is already running", c.name)
+		return nil, false
+	}
+	if err := c.queue.rebuild(); err != nil {
+		klog.Errorf("failed to restart controller %s: %v", c.name, err)
+		return nil, false
+	}
+
+	ctx, c.cancelRun = context.WithCancel(ctx)
+	c.runDone = make(chan struct{})
+	return ctx, true
+}
+
+// finish marks the end of the current run
+func (c *TypedController[K]) finish() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	c.cancelRun()
+	close(c.runDone)
+	c.cancelRun = nil
+	c.runDone = nil
+}
+
+// Stop stops the controller started by Run and waits for Run to return. The controller can be started again with
+// Run, which rebuilds the queue and the workers. Work items left in the queue are discarded. Stop must not be called
+// inside the handler.
+func (c *TypedController[K]) Stop() {
+	c.lifecycle.Lock()
+	cancel, done := c.cancelRun, c.runDone
+	c.lifecycle.Unlock()
+	if cancel == nil {
+		return
+	}
+
+	klog.Infof("stopping controller %s", c.name)
+	cancel()
+	<-done
+}
+
+// Pause stops the workers from picking up new work items, while in-flight work items are allowed to finish.
+// Work items are still enqueued and will be processed after Resume.
+func (c *TypedController[K]) Pause() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.resumeCh == nil {
+		klog.Infof("pausing controller %s", c.name)
+		c.resumeCh = make(chan struct{})
+	}
+}
+
+// Resume resumes the workers paused by Pause
+func (c *TypedController[K]) Resume() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.resumeCh != nil {
+		klog.Infof("resuming controller %s", c.name)
+		close(c.resumeCh)
+		c.resumeCh = nil
+	}
+}
+
+// Paused checks whether the controller is paused
+func (c *TypedController[K]) Paused() bool {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	return c.resumeCh != nil
+}
+
+// waitForResume blocks while the controller is paused. It returns false if ctx is done.
+func (c *TypedController[K]) waitForResume(ctx context.Context) bool {
+	c.lifecycle.Lock()
+	resumeCh := c.resumeCh
+	c.lifecycle.Unlock()
+	if resumeCh == nil {
+		return true
+	}
+
+	select {
+	case <-resumeCh:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
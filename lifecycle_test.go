@@ -0,0 +1,134 @@
+package yacht_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/dixudx/yacht/metrics"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestPauseResume(t *testing.T) {
+	h := newRestartableHarness(succeed)
+	stop := run(t, h)
+	defer stop()
+
+	h.Controller.Pause()
+	if !h.Controller.Paused() || h.Controller.Ready() == nil {
+		t.Fatal("expected the controller to be paused and not ready")
+	}
+	h.Add(namespace("a"))
+	time.Sleep(50 * time.Millisecond)
+	if calls := len(h.Calls()); calls != 0 {
+		t.Fatalf("expected no calls while paused, got %d", calls)
+	}
+
+	h.Controller.Resume()
+	eventually(t, "the work item to be processed after resuming", func() bool { return len(h.Calls()) == 1 })
+	if h.Controller.Paused() || h.Controller.Ready() != nil {
+		t.Fatal("expected the controller to be resumed and ready")
+	}
+}
+
+func TestStopWaitsForInFlightWorkItems(t *testing.T) {
+	started := make(chan struct{})
+	var lock sync.Mutex
+	var finished bool
+	h := newRestartableHarness(func(_ context.Context, _ string) (*time.Duration, error) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		finished = true
+		return nil, nil
+	})
+	h.Controller.WithDrainTimeout(5 * time.Second)
+	run(t, h)
+
+	h.Add(namespace("a"))
+	<-started
+	h.Controller.Stop()
+	lock.Lock()
+	defer lock.Unlock()
+	if !finished {
+		t.Fatal("Stop returned before the in-flight work item is done")
+	}
+}
+
+func TestRestart(t *testing.T) {
+	h := newRestartableHarness(succeed)
+	stop := run(t, h)
+	h.Add(namespace("a"))
+	h.Add(namespace("b"))
+	eventually(t, "the work items to be processed", func() bool { return len(h.Calls()) == 2 })
+
+	h.Controller.Stop()
+	// dropped while stopped, which is processed after restarting
+	h.Delete(namespace("b"))
+	h.Reset()
+
+	stop = run(t, h)
+	defer stop()
+	// the existing objects are enqueued again
+	eventually(t, "the work items to be processed after restarting", func() bool { return len(h.Calls()) == 2 })
+	keys := map[string]bool{}
+	for _, key := range h.CalledKeys() {
+		keys[key] = true
+	}
+	if !keys["a"] || !keys["b"] {
+		t.Fatalf("unexpected called keys %v after restarting", h.CalledKeys())
+	}
+}
+
+func TestRestartWithQueueFails(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	run(t, h)
+	h.Controller.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Controller.Run(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return since the queue set by WithQueue can not be rebuilt")
+	}
+}
+
+func TestRestartDiscardsQueuedWorkItems(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.RegisterWorkqueueMetrics(registry)
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Controller.WithQueueFunc(func() workqueue.RateLimitingInterface {
+		return workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "restarted")
+	})
+	run(t, h)
+	h.Controller.Pause()
+	for _, name := range []string{"a", "b", "c"} {
+		h.Add(namespace(name))
+	}
+	h.Controller.Stop()
+	// the existing objects are enqueued again after restarting, while the controller is still paused
+	defer runUntilCancelled(h)()
+
+	output := func() string {
+		var sb strings.Builder
+		if _, err := registry.WriteTo(&sb); err != nil {
+			t.Fatal(err)
+		}
+		return sb.String()
+	}
+	eventually(t, "the work items to be enqueued again", func() bool {
+		return strings.Contains(output(), `workqueue_adds_total{name="restarted"} 6`)
+	})
+	if line := `workqueue_depth{name="restarted"} 3`; !strings.Contains(output(), line) {
+		t.Fatalf("expected %q in output:\n%s", line, output())
+	}
+}
@@ -0,0 +1,256 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// InformerFactory is the minimal interface of an informer factory that can be started by the Manager,
+// such as SharedInformerFactory, DynamicSharedInformerFactory or any generated informer factory for CRDs.
+type InformerFactory interface {
+	Start(stopCh <-chan struct{})
+}
+
+// Runnable is a controller which can be registered to a Manager, such as *Controller and *TypedController[K]
+type Runnable interface {
+	Healthy() error
+	Ready() error
+
+	controllerName() string
+	validate() error
+	hasLeaderElection() bool
+	setStopCh(stopCh <-chan struct{})
+	exitsOnLeaseLoss() bool
+	run(ctx context.Context, leaseLost <-chan struct{}) error
+	shutDownQueue()
+}
+
+// Manager runs a group of Controllers under one process, sharing the same informer factories start,
+// leader election and shutdown path.
+type Manager struct {
+	// name is the name of this manager
+	name string
+	// controllers records all the registered controllers
+	controllers []Runnable
+	// informerFactories will be started before running controllers
+	informerFactories []InformerFactory
+	// le specifies the LeaderElector to use
+	le *leaderelection.LeaderElector
+	// leErrCh receives the error returned from controllers when running as the leader
+	leErrCh chan error
+	// stopLeading releases the lease when the controllers stop on a fatal error
+	stopLeading context.CancelFunc
+	// leading indicates whether the lease has been acquired
+	leading atomic.Bool
+	// stopCh is closed when the manager is asked to stop
+	stopCh <-chan struct{}
+
+	// runFlag indicates whether the manager is started
+	runFlag bool
+	// running tracks whether the controllers are running
+	running runGuard
+
+	lock sync.Mutex
+}
+
+// NewManager creates a new Manager
+func NewManager(name string) *Manager {
+	return &Manager{
+		name:    name,
+		leErrCh: make(chan error, 1),
+	}
+}
+
+// WithControllers registers controllers to the manager
+func (m *Manager) WithControllers(controllers ...Runnable) *Manager {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.runFlag {
+		panic(fmt.Errorf("can not add controllers when manager %s is running", m.name))
+	}
+
+	for _, c := range controllers {
+		if c == nil {
+			continue
+		}
+		m.controllers = append(m.controllers, c)
+	}
+	return m
+}
+
+// WithInformerFactories registers informer factories, which will be started once when the manager starts
+func (m *Manager) WithInformerFactories(factories ...InformerFactory) *Manager {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.runFlag {
+		panic(fmt.Errorf("can not add informer factories when manager %s is running", m.name))
+	}
+
+	for _, f := range factories {
+		if f == nil {
+			continue
+		}
+		m.informerFactories = append(m.informerFactories, f)
+	}
+	return m
+}
+
+// WithLeaderElection uses one leader election to get the lock for all the registered controllers
+func (m *Manager) WithLeaderElection(leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration) *Manager {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.runFlag {
+		panic(fmt.Errorf("can not mutate leaderElection when manager %s is running", m.name))
+	}
+
+	le, err := newLeaderElector(fmt.Sprintf("manager %s", m.name), leaseLock, leaseDuration, renewDeadline, retryPeriod,
+		leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				m.leading.Store(true)
+				defer m.running.enter()()
+				err := m.runControllers(ctx, ctx.Done())
+				m.leaseLost(ctx)
+				m.leErrCh <- err
+				if err != nil {
+					// give up the lease, since the controllers have stopped
+					m.stopLeading()
+				}
+			},
+			OnStoppedLeading: func() {
+				m.leading.Store(false)
+			},
+		})
+	if err != nil {
+		panic(fmt.Errorf("failed to create a LeaderElector for manager %s: %v", m.name, err))
+	}
+	m.le = le
+	return m
+}
+
+// Start starts all the informer factories and runs all the registered controllers. It will block until ctx is
+// closed, or returns the first fatal error from any controller. All the other controllers will be stopped when
+// an error occurs.
+func (m *Manager) Start(ctx context.Context) error {
+	m.lock.Lock()
+	if m.runFlag {
+		m.lock.Unlock()
+		return fmt.Errorf("manager %s has already been started", m.name)
+	}
+	m.runFlag = true
+	m.lock.Unlock()
+
+	for _, c := range m.controllers {
+		if err := c.validate(); err != nil {
+			return err
+		}
+		if c.hasLeaderElection() {
+			return fmt.Errorf("controller %s should not set leader election when running in manager %s",
+				c.controllerName(), m.name)
+		}
+	}
+
+	klog.Infof("starting manager %s with %d controllers", m.name, len(m.controllers))
+	defer klog.Infof("shutting down manager %s", m.name)
+
+	for _, f := range m.informerFactories {
+		f.Start(ctx.Done())
+	}
+	m.stopCh = ctx.Done()
+	for _, c := range m.controllers {
+		c.setStopCh(ctx.Done())
+	}
+
+	if m.le == nil {
+		defer m.running.enter()()
+		return m.runControllers(ctx, nil)
+	}
+
+	// the lease should be released only after all the controllers stop
+	leCtx, cancel := leaderContext(ctx, m.running.wait)
+	defer cancel()
+	m.stopLeading = cancel
+	m.le.Run(leCtx)
+	if ctx.Err() == nil {
+		// the LeaderElector returns before ctx is closed only after the lease has been acquired, so the controllers
+		// have been started, though maybe not entered yet
+		if err := <-m.leErrCh; err != nil {
+			return err
+		}
+		return fmt.Errorf("leader election got lost for manager %s", m.name)
+	}
+
+	m.running.wait()
+	select {
+	case err := <-m.leErrCh:
+		return err
+	default:
+		// the lease has never been acquired
+		return nil
+	}
+}
+
+// leaseLost exits the process if the lease has been lost and any controller is set with WithExitOnLeaseLoss.
+// leaderCtx is the context passed to OnStartedLeading, which is closed once the lease is lost.
+func (m *Manager) leaseLost(leaderCtx context.Context) {
+	select {
+	case <-m.stopCh:
+		return
+	default:
+	}
+	if leaderCtx.Err() == nil {
+		return
+	}
+
+	for _, c := range m.controllers {
+		if c.exitsOnLeaseLoss() {
+			klog.Errorf("manager %s lost the lease, exiting", m.name)
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+	}
+}
+
+// runControllers runs all the controllers until ctx is closed or any of the controllers fails. leaseLost is closed
+// once the lease is lost, so that the controllers cancel their workers right away.
+func (m *Manager) runControllers(ctx context.Context, leaseLost <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(m.controllers))
+	for _, c := range m.controllers {
+		wg.Add(1)
+		go func(c Runnable) {
+			defer wg.Done()
+			defer c.shutDownQueue()
+			defer func() {
+				if r := recover(); r != nil {
+					errCh <- fmt.Errorf("controller %s panicked: %v", c.controllerName(), r)
+				}
+			}()
+
+			if err := c.run(ctx, leaseLost); err != nil {
+				errCh <- err
+			}
+		}(c)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+		// stop all the other controllers
+		cancel()
+	}
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Result values used to label the reconcile metrics
+const (
+	ResultSuccess       = "success"
+	ResultError         = "error"
+	ResultTerminalError = "terminal_error"
+	ResultRequeue       = "requeue"
+	ResultRequeueAfter  = "requeue_after"
+	ResultSkip          = "skip"
+	ResultDropped       = "dropped"
+	ResultDeadLetter    = "dead_letter"
+	ResultTimeout       = "timeout"
+	ResultPanic         = "panic"
+)
+
+// Transition values used to label the leadership transitions
+const (
+	TransitionStartedLeading = "started_leading"
+	TransitionStoppedLeading = "stopped_leading"
+	TransitionNewLeader      = "new_leader"
+)
+
+// CounterMetric represents a single numerical value that only ever goes up.
+type CounterMetric interface {
+	Inc()
+}
+
+// GaugeMetric represents a single numerical value that can arbitrarily go up and down.
+type GaugeMetric interface {
+	Inc()
+	Dec()
+	Set(float64)
+}
+
+// HistogramMetric counts individual observations.
+type HistogramMetric interface {
+	Observe(float64)
+}
+
+// Provider creates the metrics of controllers. Implementations should return the same metric for the same
+// controller and labels.
+type Provider interface {
+	// NewReconcileTotalMetric returns the counter of reconciliations labelled by controller and result
+	NewReconcileTotalMetric(controller, result string) CounterMetric
+	// NewReconcileErrorsMetric returns the counter of failed reconciliations labelled by controller
+	NewReconcileErrorsMetric(controller string) CounterMetric
+	// NewReconcileRequeuesMetric returns the counter of requeued work items labelled by controller
+	NewReconcileRequeuesMetric(controller string) CounterMetric
+	// NewReconcileDurationMetric returns the histogram of reconcile durations in seconds labelled by controller
+	// and result
+	NewReconcileDurationMetric(controller, result string) HistogramMetric
+	// NewStuckHandlersMetric returns the counter of handlers which keep running past the reconcile timeout
+	// labelled by controller
+	NewStuckHandlersMetric(controller string) CounterMetric
+	// NewLeaderMetric returns the gauge indicating whether the controller holds the lease labelled by controller
+	NewLeaderMetric(controller string) GaugeMetric
+	// NewLeaderTransitionsMetric returns the counter of leadership transitions labelled by controller and
+	// transition
+	NewLeaderTransitionsMetric(controller, transition string) CounterMetric
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
+
+type noopProvider struct{}
+
+func (noopProvider) NewReconcileTotalMetric(_, _ string) CounterMetric      { return noopMetric{} }
+func (noopProvider) NewReconcileErrorsMetric(_ string) CounterMetric        { return noopMetric{} }
+func (noopProvider) NewReconcileRequeuesMetric(_ string) CounterMetric      { return noopMetric{} }
+func (noopProvider) NewReconcileDurationMetric(_, _ string) HistogramMetric { return noopMetric{} }
+func (noopProvider) NewStuckHandlersMetric(_ string) CounterMetric          { return noopMetric{} }
+func (noopProvider) NewLeaderMetric(_ string) GaugeMetric                   { return noopMetric{} }
+func (noopProvider) NewLeaderTransitionsMetric(_, _ string) CounterMetric   { return noopMetric{} }
+
+// NoopProvider is a Provider which records nothing
+var NoopProvider Provider = noopProvider{}
+
+// DefaultRegistry is the default Registry to record yacht metrics
+var DefaultRegistry = NewRegistry()
+
+// Handler returns an http.Handler serving the metrics in DefaultRegistry
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+var controllerLabels = []string{"controller"}
+var controllerResultLabels = []string{"controller", "result"}
+var controllerTransitionLabels = []string{"controller", "transition"}
+
+// NewReconcileTotalMetric implements Provider
+func (r *Registry) NewReconcileTotalMetric(controller, result string) CounterMetric {
+	return r.Counter("yacht_reconcile_total", "Total number of reconciliations per controller",
+		controllerResultLabels, controller, result)
+}
+
+// NewReconcileErrorsMetric implements Provider
+func (r *Registry) NewReconcileErrorsMetric(controller string) CounterMetric {
+	return r.Counter("yacht_reconcile_errors_total", "Total number of reconciliation errors per controller",
+		controllerLabels, controller)
+}
+
+// NewReconcileRequeuesMetric implements Provider
+func (r *Registry) NewReconcileRequeuesMetric(controller string) CounterMetric {
+	return r.Counter("yacht_reconcile_requeues_total", "Total number of requeued work items per controller",
+		controllerLabels, controller)
+}
+
+// NewReconcileDurationMetric implements Provider
+func (r *Registry) NewReconcileDurationMetric(controller, result string) HistogramMetric {
+	return r.Histogram("yacht_reconcile_duration_seconds", "Length of time per reconciliation per controller",
+		nil, controllerResultLabels, controller, result)
+}
+
+// NewStuckHandlersMetric implements Provider
+func (r *Registry) NewStuckHandlersMetric(controller string) CounterMetric {
+	return r.Counter("yacht_reconcile_stuck_handlers_total",
+		"Total number of handlers running past the reconcile timeout per controller", controllerLabels, controller)
+}
+
+// NewLeaderMetric implements Provider
+func (r *Registry) NewLeaderMetric(controller string) GaugeMetric {
+	return r.Gauge("yacht_leader", "Whether the controller holds the lease", controllerLabels, controller)
+}
+
+// NewLeaderTransitionsMetric implements Provider
+func (r *Registry) NewLeaderTransitionsMetric(controller, transition string) CounterMetric {
+	return r.Counter("yacht_leader_transitions_total", "Total number of leadership transitions per controller",
+		controllerTransitionLabels, controller, transition)
+}
+
+var _ Provider = &Registry{}
+
+// ObserveDuration records the seconds elapsed since start
+func ObserveDuration(h HistogramMetric, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// workqueueProvider exposes the workqueue metrics through a Registry
+type workqueueProvider struct {
+	registry *Registry
+}
+
+var _ workqueue.MetricsProvider = &workqueueProvider{}
+
+var queueLabels = []string{"name"}
+
+func (p *workqueueProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return p.registry.Gauge("workqueue_depth", "Current depth of workqueue", queueLabels, name)
+}
+
+func (p *workqueueProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return p.registry.Counter("workqueue_adds_total", "Total number of adds handled by workqueue", queueLabels, name)
+}
+
+func (p *workqueueProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return p.registry.Histogram("workqueue_queue_duration_seconds",
+		"How long in seconds an item stays in workqueue before being requested", nil, queueLabels, name)
+}
+
+func (p *workqueueProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return p.registry.Histogram("workqueue_work_duration_seconds",
+		"How long in seconds processing an item from workqueue takes", nil, queueLabels, name)
+}
+
+func (p *workqueueProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.registry.Gauge("workqueue_unfinished_work_seconds",
+		"How many seconds of work has been done that is in progress and hasn't been observed by "+
+			"work_duration. Large values indicate stuck threads.", queueLabels, name)
+}
+
+func (p *workqueueProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.registry.Gauge("workqueue_longest_running_processor_seconds",
+		"How many seconds has the longest running processor for workqueue been running.", queueLabels, name)
+}
+
+func (p *workqueueProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return p.registry.Counter("workqueue_retries_total", "Total number of retries handled by workqueue",
+		queueLabels, name)
+}
+
+// RegisterWorkqueueMetrics exposes the depth, latency and other metrics of all the named workqueues through the
+// registry. It sets the global workqueue.MetricsProvider, so it should be called only once and before any
+// controller is created.
+//
+// Queues of the same name share the same metrics, e.g. a controller restarted by Run rebuilds its queue with the
+// same name. The items left in the old queue are discarded before rebuilding, so they are not counted twice.
+func RegisterWorkqueueMetrics(r *Registry) {
+	workqueue.SetProvider(&workqueueProvider{registry: r})
+}
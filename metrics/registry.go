@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+)
+
+// DefaultBuckets are the default histogram buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry is a lightweight metrics registry, which serves all the registered metrics in the Prometheus text
+// exposition format.
+type Registry struct {
+	lock     sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry creates a new Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		families: map[string]*family{},
+	}
+}
+
+type family struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	buckets    []float64
+
+	lock    sync.Mutex
+	metrics map[string]*metric
+}
+
+type metric struct {
+	labels  string
+	buckets []float64
+
+	lock    sync.Mutex
+	value   float64
+	counts  []uint64
+	sum     float64
+	samples uint64
+}
+
+// Inc increases the value by 1
+func (m *metric) Inc() {
+	m.Add(1)
+}
+
+// Dec decreases the value by 1
+func (m *metric) Dec() {
+	m.Add(-1)
+}
+
+// Add adds the given value
+func (m *metric) Add(v float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.value += v
+}
+
+// Set sets the value
+func (m *metric) Set(v float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.value = v
+}
+
+// Observe adds a single observation to the histogram
+func (m *metric) Observe(v float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sum += v
+	m.samples++
+	for i := range m.counts {
+		if v <= m.buckets[i] {
+			m.counts[i]++
+		}
+	}
+}
+
+// getOrCreate returns the metric for the given label values in a family
+func (r *Registry) getOrCreate(name, help, metricType string, buckets []float64, labelNames []string,
+	labelValues ...string) *metric {
+	if len(labelNames) != len(labelValues) {
+		panic(fmt.Errorf("metric %s expects %d label values, but got %d", name, len(labelNames), len(labelValues)))
+	}
+
+	r.lock.Lock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{
+			name:       name,
+			help:       help,
+			metricType: metricType,
+			labelNames: labelNames,
+			buckets:    buckets,
+			metrics:    map[string]*metric{},
+		}
+		r.families[name] = f
+	}
+	r.lock.Unlock()
+
+	if f.metricType != metricType {
+		panic(fmt.Errorf("metric %s has already been registered as a %s", name, f.metricType))
+	}
+
+	var sb strings.Builder
+	for i, labelName := range f.labelNames {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `%s="%s"`, labelName, labelValueEscaper.Replace(labelValues[i]))
+	}
+	labels := sb.String()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	m, ok := f.metrics[labels]
+	if !ok {
+		m = &metric{
+			labels:  labels,
+			buckets: f.buckets,
+			counts:  make([]uint64, len(f.buckets)),
+		}
+		f.metrics[labels] = m
+	}
+	return m
+}
+
+// Counter returns the counter with the given name and label values
+func (r *Registry) Counter(name, help string, labelNames []string, labelValues ...string) CounterMetric {
+	return r.getOrCreate(name, help, counterType, nil, labelNames, labelValues...)
+}
+
+// Gauge returns the gauge with the given name and label values
+func (r *Registry) Gauge(name, help string, labelNames []string, labelValues ...string) GaugeMetric {
+	return r.getOrCreate(name, help, gaugeType, nil, labelNames, labelValues...)
+}
+
+// Histogram returns the histogram with the given name, buckets and label values
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames []string,
+	labelValues ...string) HistogramMetric {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return r.getOrCreate(name, help, histogramType, buckets, labelNames, labelValues...)
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.lock.RUnlock()
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	var sb strings.Builder
+	for _, f := range families {
+		f.write(&sb)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// ServeHTTP implements http.Handler, so that the registry can be scraped directly
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := r.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (f *family) write(sb *strings.Builder) {
+	f.lock.Lock()
+	metrics := make([]*metric, 0, len(f.metrics))
+	for _, m := range f.metrics {
+		metrics = append(metrics, m)
+	}
+	f.lock.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].labels < metrics[j].labels
+	})
+
+	fmt.Fprintf(sb, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", f.name, f.metricType)
+	for _, m := range metrics {
+		m.lock.Lock()
+		if f.metricType != histogramType {
+			fmt.Fprintf(sb, "%s%s %s\n", f.name, wrapLabels(m.labels), formatFloat(m.value))
+			m.lock.Unlock()
+			continue
+		}
+
+		for i, bucket := range f.buckets {
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name,
+				wrapLabels(joinLabels(m.labels, fmt.Sprintf("le=%q", formatFloat(bucket)))), m.counts[i])
+		}
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name, wrapLabels(joinLabels(m.labels, `le="+Inf"`)), m.samples)
+		fmt.Fprintf(sb, "%s_sum%s %s\n", f.name, wrapLabels(m.labels), formatFloat(m.sum))
+		fmt.Fprintf(sb, "%s_count%s %d\n", f.name, wrapLabels(m.labels), m.samples)
+		m.lock.Unlock()
+	}
+}
+
+// labelValueEscaper escapes the label values as required by the Prometheus text exposition format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func joinLabels(labels, extra string) string {
+	if len(labels) == 0 {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
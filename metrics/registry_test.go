@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Total requests", []string{"code"}, "200").Inc()
+	r.Counter("requests_total", "Total requests", []string{"code"}, "500").Inc()
+	r.Counter("requests_total", "Total requests", []string{"code"}, "200").Inc()
+	gauge := r.Gauge("in_flight", "In-flight requests", nil)
+	gauge.Set(3)
+	gauge.Dec()
+	histogram := r.Histogram("latency_seconds", "Request latency", []float64{0.1, 1}, []string{"path"}, "/")
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP in_flight In-flight requests
+# TYPE in_flight gauge
+in_flight 2
+# HELP latency_seconds Request latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/",le="0.1"} 1
+latency_seconds_bucket{path="/",le="1"} 2
+latency_seconds_bucket{path="/",le="+Inf"} 3
+latency_seconds_sum{path="/"} 5.55
+latency_seconds_count{path="/"} 3
+# HELP requests_total Total requests
+# TYPE requests_total counter
+requests_total{code="200"} 2
+requests_total{code="500"} 1
+`
+	if got := sb.String(); got != expected {
+		t.Fatalf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRegistryEscapesLabelValues(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: `plain`, expected: `plain`},
+		{value: `back\slash`, expected: `back\\slash`},
+		{value: `"quoted"`, expected: `\"quoted\"`},
+		{value: "new\nline", expected: `new\nline`},
+		// only backslash, double-quote and line feed are escaped
+		{value: "tab\tand é", expected: "tab\tand é"},
+	}
+	for _, tt := range tests {
+		r := NewRegistry()
+		r.Counter("total", "Total", []string{"name"}, tt.value).Inc()
+		var sb strings.Builder
+		if _, err := r.WriteTo(&sb); err != nil {
+			t.Fatal(err)
+		}
+		if line := `total{name="` + tt.expected + `"} 1`; !strings.Contains(sb.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestRegistryReturnsSameMetric(t *testing.T) {
+	r := NewRegistry()
+	r.NewReconcileTotalMetric("test", ResultSuccess).Inc()
+	r.NewReconcileTotalMetric("test", ResultSuccess).Inc()
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if line := `yacht_reconcile_total{controller="test",result="success"} 2`; !strings.Contains(sb.String(), line) {
+		t.Fatalf("expected %q in output:\n%s", line, sb.String())
+	}
+}
+
+func TestRegistryPanicsOnTypeConflict(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("total", "Total", nil)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic registering a counter as a gauge")
+		}
+	}()
+	r.Gauge("total", "Total", nil)
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("total", "Total", nil).Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	if !strings.Contains(rec.Body.String(), "total 1\n") {
+		t.Fatalf("unexpected body:\n%s", rec.Body.String())
+	}
+}
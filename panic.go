@@ -0,0 +1,60 @@
+package yacht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"k8s.io/klog/v2"
+)
+
+// panicError is returned when the handler panics
+type panicError struct {
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("observed a panic: %v", e.value)
+}
+
+// IsPanicError checks whether err is returned because the handler panics
+func IsPanicError(err error) bool {
+	var pe *panicError
+	return errors.As(err, &pe)
+}
+
+// WithMaxPanics crashes the process once the handler has panicked maxPanics times in total. A panic in the
+// handler is always recovered, logged with the stack and retried with backoff, so that a single malformed object
+// can not kill the controller. Zero maxPanics means never crashing.
+func (c *TypedController[K]) WithMaxPanics(maxPanics int) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate maxPanics when controller %s is running", c.name))
+	}
+	if maxPanics < 0 {
+		panic(fmt.Errorf("can not set negative maxPanics %d", maxPanics))
+	}
+
+	c.maxPanics = int64(maxPanics)
+	return c
+}
+
+// reconcileWithRecover calls the handler and recovers from its panic
+func (c *TypedController[K]) reconcileWithRecover(ctx context.Context, item K) (result Result, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		err = &panicError{value: r}
+		// always log the stack, which is the only clue to the panic
+		klog.ErrorS(err, "handler panicked", "controller", c.name, "key", item, "stack", string(debug.Stack()))
+		if panics := c.panics.Add(1); c.maxPanics > 0 && panics >= c.maxPanics {
+			panic(fmt.Errorf("controller %s crashed after %d panics, the last one on work item %v: %v",
+				c.name, panics, item, r))
+		}
+	}()
+
+	return c.reconcileWithTimeout(ctx, item)
+}
@@ -0,0 +1,61 @@
+package yacht_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dixudx/yacht/metrics"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func panicOn(panicKey string) func(_ context.Context, key string) (*time.Duration, error) {
+	return func(_ context.Context, key string) (*time.Duration, error) {
+		if key == panicKey {
+			panic("malformed object")
+		}
+		return nil, nil
+	}
+}
+
+func TestPanicRecovered(t *testing.T) {
+	registry := metrics.NewRegistry()
+	h := yachttest.NewHarness[string]("panic", panicOn("a"))
+	h.Controller.WithMetricsProvider(registry)
+	h.Add(namespace("a"))
+	h.Add(namespace("b"))
+	if n := h.ProcessAll(context.Background()); n != 2 {
+		t.Fatalf("expected 2 processed work items, got %d", n)
+	}
+
+	// the panicked work item is retried with backoff, while the other one is processed
+	if rateLimited := h.Queue.RateLimited(); len(rateLimited) != 1 || rateLimited[0] != "a" {
+		t.Fatalf("unexpected rate limited work items %v", rateLimited)
+	}
+	if keys := h.CalledKeys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+	var sb strings.Builder
+	if _, err := registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if line := `yacht_reconcile_total{controller="panic",result="panic"} 1`; !strings.Contains(sb.String(), line) {
+		t.Fatalf("expected %q in output:\n%s", line, sb.String())
+	}
+}
+
+func TestMaxPanics(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", panicOn("a"))
+	h.Controller.WithMaxPanics(2)
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+	h.Flush()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected the controller to crash on the second panic")
+		}
+	}()
+	h.ProcessAll(context.Background())
+}
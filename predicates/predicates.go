@@ -0,0 +1,160 @@
+// Package predicates provides composable filters, which can be set with Controller.WithEnqueueFilterFunc.
+//
+// All the filters follow the convention of yacht.EnqueueFilterFunc, where oldObj is nil on additions and newObj is
+// nil on deletions. Unless stated otherwise, a filter is evaluated on newObj for additions and updates, and on
+// oldObj for deletions.
+package predicates
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/dixudx/yacht"
+)
+
+// And passes the objects only if all the filters pass. Nil filters are ignored.
+func And(filters ...yacht.EnqueueFilterFunc) yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		for _, filter := range filters {
+			if filter == nil {
+				continue
+			}
+			ok, err := filter(oldObj, newObj)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Or passes the objects if any of the filters passes. Nil filters are ignored.
+func Or(filters ...yacht.EnqueueFilterFunc) yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		for _, filter := range filters {
+			if filter == nil {
+				continue
+			}
+			ok, err := filter(oldObj, newObj)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Not negates the filter. Errors are passed through. A nil filter, which passes everything in And, is negated into
+// passing nothing.
+func Not(filter yacht.EnqueueFilterFunc) yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		if filter == nil {
+			return false, nil
+		}
+		ok, err := filter(oldObj, newObj)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	}
+}
+
+// objectFilter builds a filter evaluated on the current object
+func objectFilter(fn func(obj metav1.Object) bool) yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		obj := newObj
+		if obj == nil {
+			obj = oldObj
+		}
+		if obj == nil {
+			return false, nil
+		}
+
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false, err
+		}
+		return fn(accessor), nil
+	}
+}
+
+// InNamespaces passes the objects in any of the namespaces
+func InNamespaces(namespaces ...string) yacht.EnqueueFilterFunc {
+	nsSet := sets.New(namespaces...)
+	return objectFilter(func(obj metav1.Object) bool {
+		return nsSet.Has(obj.GetNamespace())
+	})
+}
+
+// MatchLabels passes the objects whose labels match the selector
+func MatchLabels(selector labels.Selector) yacht.EnqueueFilterFunc {
+	return objectFilter(func(obj metav1.Object) bool {
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	})
+}
+
+// HasAnnotation passes the objects with the annotation key
+func HasAnnotation(key string) yacht.EnqueueFilterFunc {
+	return objectFilter(func(obj metav1.Object) bool {
+		_, ok := obj.GetAnnotations()[key]
+		return ok
+	})
+}
+
+// OwnedBy passes the objects owned by an owner of ownerGVK. Only the group and kind are compared. If isController
+// is true, only the controller owner is considered.
+func OwnedBy(ownerGVK schema.GroupVersionKind, isController bool) yacht.EnqueueFilterFunc {
+	return objectFilter(func(obj metav1.Object) bool {
+		for _, ref := range obj.GetOwnerReferences() {
+			if isController && (ref.Controller == nil || !*ref.Controller) {
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil {
+				continue
+			}
+			if gv.Group == ownerGVK.Group && ref.Kind == ownerGVK.Kind {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// NotBeingDeleted passes the objects without a deletionTimestamp on additions and updates. Deletions always pass.
+func NotBeingDeleted() yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		if newObj == nil {
+			return true, nil
+		}
+		return objectFilter(func(obj metav1.Object) bool {
+			return obj.GetDeletionTimestamp().IsZero()
+		})(oldObj, newObj)
+	}
+}
+
+// GenerationChanged passes the updates changing metadata.generation, which skips the status-only updates.
+// Additions and deletions always pass.
+func GenerationChanged() yacht.EnqueueFilterFunc {
+	return func(oldObj, newObj interface{}) (bool, error) {
+		if oldObj == nil || newObj == nil {
+			return true, nil
+		}
+
+		oldMeta, err := meta.Accessor(oldObj)
+		if err != nil {
+			return false, err
+		}
+		newMeta, err := meta.Accessor(newObj)
+		if err != nil {
+			return false, err
+		}
+		return oldMeta.GetGeneration() != newMeta.GetGeneration(), nil
+	}
+}
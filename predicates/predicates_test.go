@@ -0,0 +1,154 @@
+package predicates_test
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/predicates"
+)
+
+var (
+	pass yacht.EnqueueFilterFunc = func(_, _ interface{}) (bool, error) { return true, nil }
+	skip yacht.EnqueueFilterFunc = func(_, _ interface{}) (bool, error) { return false, nil }
+	fail yacht.EnqueueFilterFunc = func(_, _ interface{}) (bool, error) { return true, errors.New("failed") }
+)
+
+func pod(mutate func(pod *corev1.Pod)) *corev1.Pod {
+	p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"}}
+	if mutate != nil {
+		mutate(p)
+	}
+	return p
+}
+
+func TestCompositeFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   yacht.EnqueueFilterFunc
+		expected bool
+		err      bool
+	}{
+		{name: "and", filter: predicates.And(pass, pass), expected: true},
+		{name: "and skipped", filter: predicates.And(pass, skip), expected: false},
+		{name: "and without filters", filter: predicates.And(), expected: true},
+		{name: "and ignores nil", filter: predicates.And(nil, pass), expected: true},
+		{name: "and error", filter: predicates.And(pass, fail), err: true},
+		{name: "or", filter: predicates.Or(skip, pass), expected: true},
+		{name: "or skipped", filter: predicates.Or(skip, skip), expected: false},
+		{name: "or without filters", filter: predicates.Or(), expected: false},
+		{name: "or ignores nil", filter: predicates.Or(nil, skip), expected: false},
+		{name: "or error", filter: predicates.Or(skip, fail), err: true},
+		{name: "not", filter: predicates.Not(skip), expected: true},
+		{name: "not nil", filter: predicates.Not(nil), expected: false},
+		{name: "not error", filter: predicates.Not(fail), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.filter(nil, pod(nil))
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, ok)
+			}
+		})
+	}
+}
+
+func TestObjectFilters(t *testing.T) {
+	labelled := pod(func(p *corev1.Pod) { p.Labels = map[string]string{"app": "yacht"} })
+	annotated := pod(func(p *corev1.Pod) { p.Annotations = map[string]string{"yacht": ""} })
+	owned := pod(func(p *corev1.Pod) {
+		p.OwnerReferences = []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", Controller: ptr.To(true)},
+			{APIVersion: "v1", Kind: "ConfigMap", Name: "cm"},
+		}
+	})
+	replicaSet := schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "ReplicaSet"}
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	tests := []struct {
+		name     string
+		filter   yacht.EnqueueFilterFunc
+		obj      interface{}
+		expected bool
+	}{
+		{name: "in namespaces", filter: predicates.InNamespaces("kube-system", "default"), obj: pod(nil), expected: true},
+		{name: "not in namespaces", filter: predicates.InNamespaces("kube-system"), obj: pod(nil), expected: false},
+		{name: "match labels", filter: predicates.MatchLabels(labels.SelectorFromSet(labels.Set{"app": "yacht"})),
+			obj: labelled, expected: true},
+		{name: "mismatch labels", filter: predicates.MatchLabels(labels.SelectorFromSet(labels.Set{"app": "yacht"})),
+			obj: pod(nil), expected: false},
+		{name: "has annotation", filter: predicates.HasAnnotation("yacht"), obj: annotated, expected: true},
+		{name: "no annotation", filter: predicates.HasAnnotation("yacht"), obj: pod(nil), expected: false},
+		{name: "owned by", filter: predicates.OwnedBy(replicaSet, false), obj: owned, expected: true},
+		{name: "owned by controller", filter: predicates.OwnedBy(replicaSet, true), obj: owned, expected: true},
+		{name: "owned by non-controller", filter: predicates.OwnedBy(configMap, false), obj: owned, expected: true},
+		{name: "not owned by controller", filter: predicates.OwnedBy(configMap, true), obj: owned, expected: false},
+		{name: "not owned", filter: predicates.OwnedBy(replicaSet, false), obj: pod(nil), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, objs := range [][2]interface{}{{nil, tt.obj}, {pod(nil), tt.obj}, {tt.obj, nil}} {
+				ok, err := tt.filter(objs[0], objs[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != tt.expected {
+					t.Fatalf("expected %v for %v, got %v", tt.expected, objs, ok)
+				}
+			}
+		})
+	}
+
+	if _, err := predicates.InNamespaces("default")(nil, "not an object"); err == nil {
+		t.Fatal("expected an error for a non-object")
+	}
+}
+
+func TestNotBeingDeleted(t *testing.T) {
+	deleting := pod(func(p *corev1.Pod) { p.DeletionTimestamp = ptr.To(metav1.Now()) })
+	filter := predicates.NotBeingDeleted()
+	for _, tt := range []struct {
+		name           string
+		oldObj, newObj interface{}
+		expected       bool
+	}{
+		{name: "added", newObj: pod(nil), expected: true},
+		{name: "added being deleted", newObj: deleting, expected: false},
+		{name: "updated being deleted", oldObj: pod(nil), newObj: deleting, expected: false},
+		{name: "deleted", oldObj: deleting, expected: true},
+	} {
+		if ok, err := filter(tt.oldObj, tt.newObj); err != nil || ok != tt.expected {
+			t.Errorf("%s: expected %v, got %v with error %v", tt.name, tt.expected, ok, err)
+		}
+	}
+}
+
+func TestGenerationChanged(t *testing.T) {
+	generation := func(generation int64) *corev1.Pod {
+		return pod(func(p *corev1.Pod) { p.Generation = generation })
+	}
+	filter := predicates.GenerationChanged()
+	for _, tt := range []struct {
+		name           string
+		oldObj, newObj interface{}
+		expected       bool
+	}{
+		{name: "added", newObj: generation(1), expected: true},
+		{name: "spec updated", oldObj: generation(1), newObj: generation(2), expected: true},
+		{name: "status updated", oldObj: generation(1), newObj: generation(1), expected: false},
+		{name: "deleted", oldObj: generation(1), expected: true},
+	} {
+		if ok, err := filter(tt.oldObj, tt.newObj); err != nil || ok != tt.expected {
+			t.Errorf("%s: expected %v, got %v with error %v", tt.name, tt.expected, ok, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package yacht
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Priorities of the work items. Work items with higher priorities are handed out first.
+const (
+	// PriorityResync is the priority of the work items enqueued by periodic resyncs, where the ResourceVersion
+	// is unchanged
+	PriorityResync = -10
+	// PriorityDefault is the priority of the work items enqueued by real Add/Update/Delete events
+	PriorityDefault = 0
+	// PriorityHigh is a priority for urgent work items
+	PriorityHigh = 10
+)
+
+// PriorityQueue is a rate limited work queue which hands out work items with higher priorities first, and in FIFO
+// order among the same priority
+type PriorityQueue interface {
+	workqueue.RateLimitingInterface
+	// AddWithPriority adds item with priority. The priority of an item already waiting in the queue will be
+	// raised if lower.
+	AddWithPriority(item interface{}, priority int)
+}
+
+type rateLimitingPriorityQueue struct {
+	workqueue.RateLimitingInterface
+	queue *priorityQueue
+}
+
+// NewPriorityQueue creates a PriorityQueue, which can be used by WithQueueFunc or WithQueue. Work items put back by
+// Add, AddAfter and AddRateLimited while being processed keep the priorities they were handed out with, while the
+// other ones are added with PriorityDefault. The metrics of the queue depth and latency are not recorded.
+func NewPriorityQueue(rateLimiter workqueue.RateLimiter, name string) PriorityQueue {
+	queue := newPriorityQueue()
+	return &rateLimitingPriorityQueue{
+		RateLimitingInterface: workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+			Name: name,
+			DelayingQueue: workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{
+				Name:  name,
+				Queue: queue,
+			}),
+		}),
+		queue: queue,
+	}
+}
+
+func (q *rateLimitingPriorityQueue) AddWithPriority(item interface{}, priority int) {
+	q.queue.AddWithPriority(item, priority)
+}
+
+func (q *rateLimitingPriorityQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.queue.keepPriority(item)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+func (q *rateLimitingPriorityQueue) AddRateLimited(item interface{}) {
+	q.queue.keepPriority(item)
+	q.RateLimitingInterface.AddRateLimited(item)
+}
+
+// priorityItem is a work item waiting in the priorityQueue
+type priorityItem struct {
+	item     interface{}
+	priority int
+	// seq keeps the FIFO order among the same priority
+	seq   uint64
+	index int
+}
+
+// priorityHeap implements heap.Interface
+type priorityHeap []*priorityItem
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *priorityHeap) Push(x interface{}) {
+	item := x.(*priorityItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *priorityHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// priorityQueue is a workqueue.Interface ordered by priorities. Like workqueue.Type, an item is never processed
+// concurrently, and an item added while being processed will be requeued once done.
+type priorityQueue struct {
+	cond *sync.Cond
+
+	seq     uint64
+	queue   priorityHeap
+	waiting map[interface{}]*priorityItem
+	// dirty records the priorities of the items added while being processed
+	dirty map[interface{}]int
+	// processing records the priorities of the items being processed
+	processing map[interface{}]int
+	// delayed records the priorities of the items put back with a delay, which are used when they are added
+	delayed map[interface{}]int
+
+	shuttingDown bool
+	drain        bool
+}
+
+var _ workqueue.Interface = &priorityQueue{}
+
+func newPriorityQueue() *priorityQueue {
+	return &priorityQueue{
+		cond:       sync.NewCond(&sync.Mutex{}),
+		waiting:    map[interface{}]*priorityItem{},
+		dirty:      map[interface{}]int{},
+		processing: map[interface{}]int{},
+		delayed:    map[interface{}]int{},
+	}
+}
+
+// Add adds item with the priority kept for it, which is the priority it was handed out with if it is being
+// processed or has been put back with a delay, otherwise PriorityDefault
+func (q *priorityQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	priority, ok := q.delayed[item]
+	if ok {
+		delete(q.delayed, item)
+	} else if priority, ok = q.processing[item]; !ok {
+		priority = PriorityDefault
+	}
+	q.add(item, priority)
+}
+
+// keepPriority records the priority of item being processed, which will be used when it is added after a delay
+func (q *priorityQueue) keepPriority(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	priority, ok := q.processing[item]
+	if !ok {
+		return
+	}
+	if delayed, ok := q.delayed[item]; !ok || priority > delayed {
+		q.delayed[item] = priority
+	}
+}
+
+func (q *priorityQueue) AddWithPriority(item interface{}, priority int) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.add(item, priority)
+}
+
+// add adds item with priority, which should be called with the lock held
+func (q *priorityQueue) add(item interface{}, priority int) {
+	if q.shuttingDown {
+		return
+	}
+
+	if waiting, ok := q.waiting[item]; ok {
+		if priority > waiting.priority {
+			waiting.priority = priority
+			heap.Fix(&q.queue, waiting.index)
+		}
+		return
+	}
+	if _, ok := q.processing[item]; ok {
+		if dirty, ok := q.dirty[item]; !ok || priority > dirty {
+			q.dirty[item] = priority
+		}
+		return
+	}
+	q.push(item, priority)
+}
+
+// push puts item onto the heap, which should be called with the lock held
+func (q *priorityQueue) push(item interface{}, priority int) {
+	q.seq++
+	waiting := &priorityItem{item: item, priority: priority, seq: q.seq}
+	heap.Push(&q.queue, waiting)
+	q.waiting[item] = waiting
+	q.cond.Signal()
+}
+
+func (q *priorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.queue.Len()
+}
+
+func (q *priorityQueue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for q.queue.Len() == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if q.queue.Len() == 0 {
+		// we must be shutting down
+		return nil, true
+	}
+
+	waiting := heap.Pop(&q.queue).(*priorityItem)
+	delete(q.waiting, waiting.item)
+	q.processing[waiting.item] = waiting.priority
+	return waiting.item, false
+}
+
+func (q *priorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.processing, item)
+	if priority, ok := q.dirty[item]; ok {
+		delete(q.dirty, item)
+		q.push(item, priority)
+	} else if len(q.processing) == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+func (q *priorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *priorityQueue) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+
+	for len(q.processing) > 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+func (q *priorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+// EnqueueWithPriority works like Enqueue, while the keys are put onto the work queue with priority if the work
+// queue is a PriorityQueue.
+func (c *TypedController[K]) EnqueueWithPriority(obj interface{}, priority int) {
+	c.enqueueKeys(obj, c.enqueueKeysFunc, true, c.addWithPriority(priority))
+}
+
+// isResync checks whether an update event comes from a periodic resync, where the ResourceVersion is unchanged
+func isResync(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() != "" && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
+}
@@ -0,0 +1,163 @@
+package yacht_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func newPriorityQueue() yacht.PriorityQueue {
+	return yacht.NewPriorityQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0), "")
+}
+
+// getAll gets n items off the queue and marks them done
+func getAll(t *testing.T, q workqueue.Interface, n int) []interface{} {
+	t.Helper()
+	var items []interface{}
+	for i := 0; i < n; i++ {
+		item, shutdown := q.Get()
+		if shutdown {
+			t.Fatalf("unexpected shutdown after getting %v", items)
+		}
+		items = append(items, item)
+		q.Done(item)
+	}
+	return items
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := newPriorityQueue()
+	q.AddWithPriority("resync-1", yacht.PriorityResync)
+	q.Add("default-1")
+	q.AddWithPriority("high", yacht.PriorityHigh)
+	q.AddWithPriority("resync-2", yacht.PriorityResync)
+	q.Add("default-2")
+	// raised while waiting, which keeps its place among the same priority
+	q.AddWithPriority("resync-2", yacht.PriorityDefault)
+	// never lowered
+	q.AddWithPriority("high", yacht.PriorityResync)
+
+	if q.Len() != 5 {
+		t.Fatalf("expected 5 items, got %d", q.Len())
+	}
+	want := []interface{}{"high", "default-1", "resync-2", "default-2", "resync-1"}
+	if got := getAll(t, q, 5); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPriorityQueueAddWhileProcessing(t *testing.T) {
+	q := newPriorityQueue()
+	q.Add("a")
+	item, _ := q.Get()
+	q.AddWithPriority("a", yacht.PriorityHigh)
+	if q.Len() != 0 {
+		t.Fatalf("an item being processed should not be handed out again, got %d items", q.Len())
+	}
+
+	q.Add("b")
+	q.Done(item)
+	want := []interface{}{"a", "b"}
+	if got := getAll(t, q, 2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPriorityQueueKeepsPriorityOnRequeue(t *testing.T) {
+	for name, requeue := range map[string]func(q yacht.PriorityQueue, item interface{}){
+		"Add":            func(q yacht.PriorityQueue, item interface{}) { q.Add(item) },
+		"AddAfter":       func(q yacht.PriorityQueue, item interface{}) { q.AddAfter(item, time.Millisecond) },
+		"AddRateLimited": func(q yacht.PriorityQueue, item interface{}) { q.AddRateLimited(item) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			q := newPriorityQueue()
+			q.AddWithPriority("resync", yacht.PriorityResync)
+			item, _ := q.Get()
+			requeue(q, item)
+			q.Done(item)
+			for q.Len() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			q.Add("default")
+			want := []interface{}{"default", "resync"}
+			if got := getAll(t, q, 2); !reflect.DeepEqual(got, want) {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestPriorityQueueShutDownWithDrain(t *testing.T) {
+	q := newPriorityQueue()
+	q.Add("a")
+	q.Add("b")
+	item, _ := q.Get()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		q.ShutDownWithDrain()
+	}()
+	select {
+	case <-drained:
+		t.Fatal("ShutDownWithDrain returned before the item being processed is done")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !q.ShuttingDown() {
+		t.Fatal("expected the queue to be shutting down")
+	}
+
+	q.Done(item)
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ShutDownWithDrain did not return after the item is done")
+	}
+	q.Add("c")
+	if q.Len() != 1 {
+		t.Fatalf("items should not be added after shutdown, got %d items", q.Len())
+	}
+}
+
+func TestControllerProcessesByPriority(t *testing.T) {
+	var failed bool
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, key string) (*time.Duration, error) {
+		if key == "resync" && !failed {
+			failed = true
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	h.Controller.WithQueue(newPriorityQueue())
+
+	resync := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "resync", ResourceVersion: "1"}}
+	h.Update(resync, resync)
+	h.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	h.Controller.EnqueueWithPriority(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "high"}}, yacht.PriorityHigh)
+	for i := 0; i < 3; i++ {
+		h.Controller.ProcessNextWorkItem(context.Background())
+	}
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"high", "default", "resync"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+
+	// the failed resync is retried after the new events
+	h.Reset()
+	h.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "new"}})
+	for i := 0; i < 2; i++ {
+		h.Controller.ProcessNextWorkItem(context.Background())
+	}
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"new", "resync"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
@@ -0,0 +1,200 @@
+package yacht
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// TypedRateLimitingInterface is a rate limited work queue storing work items of type K
+type TypedRateLimitingInterface[K comparable] interface {
+	Add(item K)
+	Len() int
+	Get() (item K, shutdown bool)
+	Done(item K)
+	ShutDown()
+	ShutDownWithDrain()
+	ShuttingDown() bool
+	AddAfter(item K, duration time.Duration)
+	AddRateLimited(item K)
+	Forget(item K)
+	NumRequeues(item K) int
+	// AddWithPriority adds item with priority if the underlying queue is a PriorityQueue, otherwise it works
+	// like Add
+	AddWithPriority(item K, priority int)
+}
+
+// typedQueue wraps a workqueue.RateLimitingInterface to store work items of type K
+type typedQueue[K comparable] struct {
+	queue workqueue.RateLimitingInterface
+}
+
+// NewTypedRateLimitingQueue wraps a workqueue.RateLimitingInterface into a TypedRateLimitingInterface.
+// Work items put onto the underlying queue directly which are not of type K will be dropped.
+func NewTypedRateLimitingQueue[K comparable](queue workqueue.RateLimitingInterface) TypedRateLimitingInterface[K] {
+	return &typedQueue[K]{queue: queue}
+}
+
+func (q *typedQueue[K]) Add(item K) {
+	q.queue.Add(item)
+}
+
+func (q *typedQueue[K]) AddWithPriority(item K, priority int) {
+	if pq, ok := q.queue.(PriorityQueue); ok {
+		pq.AddWithPriority(item, priority)
+		return
+	}
+	q.queue.Add(item)
+}
+
+func (q *typedQueue[K]) Len() int {
+	return q.queue.Len()
+}
+
+func (q *typedQueue[K]) Get() (K, bool) {
+	for {
+		item, shutdown := q.queue.Get()
+		if shutdown {
+			var zero K
+			return zero, true
+		}
+
+		key, ok := item.(K)
+		if ok {
+			return key, false
+		}
+
+		utilruntime.HandleError(fmt.Errorf("dropping work item %v of unexpected type %T", item, item))
+		q.queue.Forget(item)
+		q.queue.Done(item)
+	}
+}
+
+func (q *typedQueue[K]) Done(item K) {
+	q.queue.Done(item)
+}
+
+func (q *typedQueue[K]) ShutDown() {
+	q.queue.ShutDown()
+}
+
+func (q *typedQueue[K]) ShutDownWithDrain() {
+	q.queue.ShutDownWithDrain()
+}
+
+func (q *typedQueue[K]) ShuttingDown() bool {
+	return q.queue.ShuttingDown()
+}
+
+func (q *typedQueue[K]) AddAfter(item K, duration time.Duration) {
+	q.queue.AddAfter(item, duration)
+}
+
+func (q *typedQueue[K]) AddRateLimited(item K) {
+	q.queue.AddRateLimited(item)
+}
+
+func (q *typedQueue[K]) Forget(item K) {
+	q.queue.Forget(item)
+}
+
+func (q *typedQueue[K]) NumRequeues(item K) int {
+	return q.queue.NumRequeues(item)
+}
+
+// restartableQueue delegates to a TypedRateLimitingInterface, which can be replaced with a new one once shut down,
+// so that the controller can be restarted
+type restartableQueue[K comparable] struct {
+	queue atomic.Pointer[TypedRateLimitingInterface[K]]
+	// newQueue creates a new queue on restart, which is nil if the queue can not be rebuilt
+	newQueue func() TypedRateLimitingInterface[K]
+}
+
+func newRestartableQueue[K comparable](queue TypedRateLimitingInterface[K],
+	newQueue func() TypedRateLimitingInterface[K]) *restartableQueue[K] {
+	q := &restartableQueue[K]{newQueue: newQueue}
+	q.queue.Store(&queue)
+	return q
+}
+
+// rebuildable checks whether the queue can be rebuilt
+func (q *restartableQueue[K]) rebuildable() bool {
+	return q.newQueue != nil
+}
+
+// rebuild replaces the shut down queue with a new one. The items left in the old queue are discarded first, so
+// that the metrics shared by queues of the same name, e.g. the depth, don't count them anymore.
+func (q *restartableQueue[K]) rebuild() error {
+	old := q.get()
+	if !old.ShuttingDown() {
+		return nil
+	}
+	if q.newQueue == nil {
+		return fmt.Errorf("queue set by WithQueue can not be rebuilt, please use WithQueueFunc instead")
+	}
+	for old.Len() > 0 {
+		item, shutdown := old.Get()
+		if shutdown {
+			break
+		}
+		old.Done(item)
+	}
+	queue := q.newQueue()
+	q.queue.Store(&queue)
+	return nil
+}
+
+func (q *restartableQueue[K]) get() TypedRateLimitingInterface[K] {
+	return *q.queue.Load()
+}
+
+func (q *restartableQueue[K]) Add(item K) {
+	q.get().Add(item)
+}
+
+func (q *restartableQueue[K]) AddWithPriority(item K, priority int) {
+	q.get().AddWithPriority(item, priority)
+}
+
+func (q *restartableQueue[K]) Len() int {
+	return q.get().Len()
+}
+
+func (q *restartableQueue[K]) Get() (K, bool) {
+	return q.get().Get()
+}
+
+func (q *restartableQueue[K]) Done(item K) {
+	q.get().Done(item)
+}
+
+func (q *restartableQueue[K]) ShutDown() {
+	q.get().ShutDown()
+}
+
+func (q *restartableQueue[K]) ShutDownWithDrain() {
+	q.get().ShutDownWithDrain()
+}
+
+func (q *restartableQueue[K]) ShuttingDown() bool {
+	return q.get().ShuttingDown()
+}
+
+func (q *restartableQueue[K]) AddAfter(item K, duration time.Duration) {
+	q.get().AddAfter(item, duration)
+}
+
+func (q *restartableQueue[K]) AddRateLimited(item K) {
+	q.get().AddRateLimited(item)
+}
+
+func (q *restartableQueue[K]) Forget(item K) {
+	q.get().Forget(item)
+}
+
+func (q *restartableQueue[K]) NumRequeues(item K) int {
+	return q.get().NumRequeues(item)
+}
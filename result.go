@@ -0,0 +1,78 @@
+package yacht
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Result is the outcome of a reconciliation. The zero Result means the work item is done and its rate limiting
+// history is forgotten.
+type Result struct {
+	// Requeue puts the work item back on the work queue with rate limiting
+	Requeue bool
+	// RequeueAfter puts the work item back on the work queue after the duration, which takes precedence over
+	// Requeue. Its rate limiting history is forgotten.
+	RequeueAfter time.Duration
+	// Skip marks the work item done without forgetting its rate limiting history, e.g. the work item is not
+	// supposed to be processed by this controller.
+	Skip bool
+}
+
+// TypedReconcileFunc processes the work item with a key of type K and returns the Result. When an error is
+// returned, the work item is put back on the work queue with rate limiting, or after Result.RequeueAfter if set,
+// unless the error is a TerminalError.
+type TypedReconcileFunc[K comparable] func(ctx context.Context, key K) (Result, error)
+
+type ReconcileFunc = TypedReconcileFunc[interface{}]
+
+// terminalError is an error that should not be retried
+type terminalError struct {
+	err error
+}
+
+func (e *terminalError) Error() string {
+	return "terminal error: " + e.err.Error()
+}
+
+func (e *terminalError) Unwrap() error {
+	return e.err
+}
+
+// TerminalError wraps err, so that the work item will not be retried. The error is still logged, recorded as a
+// Warning event and counted in metrics.
+func TerminalError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &terminalError{err: err}
+}
+
+// IsTerminalError checks whether err is or wraps a TerminalError
+func IsTerminalError(err error) bool {
+	var te *terminalError
+	return errors.As(err, &te)
+}
+
+// ReconcileFuncFor converts a TypedHandlerFunc into a TypedReconcileFunc. The behaviors of the handler are kept,
+// where a NotFound error is treated as done.
+func ReconcileFuncFor[K comparable](handlerFunc TypedHandlerFunc[K]) TypedReconcileFunc[K] {
+	return func(ctx context.Context, key K) (Result, error) {
+		requeueAfter, err := handlerFunc(ctx, key)
+		if apierrors.IsNotFound(err) {
+			return Result{}, nil
+		}
+
+		var result Result
+		if requeueAfter != nil {
+			result.RequeueAfter = *requeueAfter
+			if result.RequeueAfter <= 0 {
+				// AddAfter with a non-positive duration adds the item immediately
+				result.RequeueAfter = time.Nanosecond
+			}
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,93 @@
+package yacht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// reconcileTimeoutError is returned when the handler exceeds the reconcile timeout
+type reconcileTimeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+func (e *reconcileTimeoutError) Error() string {
+	return fmt.Sprintf("reconcile timed out after %v: %v", e.timeout, e.err)
+}
+
+func (e *reconcileTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// IsReconcileTimeout checks whether err is returned because the handler exceeds the reconcile timeout
+func IsReconcileTimeout(err error) bool {
+	var te *reconcileTimeoutError
+	return errors.As(err, &te)
+}
+
+// WithReconcileTimeout bounds each call of the handler with a deadline. Once the deadline is exceeded, the
+// context passed to the handler is cancelled and the work item is retried with backoff, regardless of the
+// errorPolicy. Zero timeout means no deadline.
+func (c *TypedController[K]) WithReconcileTimeout(timeout time.Duration) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate reconcileTimeout when controller %s is running", c.name))
+	}
+	if timeout < 0 {
+		panic(fmt.Errorf("can not set negative reconcileTimeout %v", timeout))
+	}
+
+	c.reconcileTimeout = timeout
+	return c
+}
+
+// WithStuckHandlerDetection reports handlers which ignore the cancellation and keep running longer than
+// gracePeriod past the reconcile timeout. Such handlers are logged and counted, but never abandoned, so that a
+// work item is never processed concurrently. It takes effect only with WithReconcileTimeout.
+func (c *TypedController[K]) WithStuckHandlerDetection(gracePeriod time.Duration) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate stuckGracePeriod when controller %s is running", c.name))
+	}
+	if gracePeriod < 0 {
+		panic(fmt.Errorf("can not set negative stuckGracePeriod %v", gracePeriod))
+	}
+
+	c.detectStuckHandlers = true
+	c.stuckGracePeriod = gracePeriod
+	return c
+}
+
+// reconcileWithTimeout calls the handler with a deadline-bound context if the reconcile timeout is set
+func (c *TypedController[K]) reconcileWithTimeout(ctx context.Context, item K) (Result, error) {
+	if c.reconcileTimeout <= 0 {
+		return c.reconcile(ctx, item)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.reconcileTimeout)
+	defer cancel()
+
+	if c.detectStuckHandlers {
+		start := time.Now()
+		watchdog := time.AfterFunc(c.reconcileTimeout+c.stuckGracePeriod, func() {
+			klog.Warningf("handler of controller %s keeps running on work item %v for %v, ignoring the cancellation",
+				c.name, item, time.Since(start))
+			c.metricsProvider.NewStuckHandlersMetric(c.name).Inc()
+		})
+		defer func() {
+			if !watchdog.Stop() {
+				klog.Warningf("stuck handler of controller %s returned on work item %v after %v",
+					c.name, item, time.Since(start))
+			}
+		}()
+	}
+
+	result, err := c.reconcile(ctx, item)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		klog.Warningf("controller %s exceeded reconcile timeout %v on work item %v", c.name, c.reconcileTimeout, item)
+		return result, &reconcileTimeoutError{timeout: c.reconcileTimeout, err: err}
+	}
+	return result, err
+}
@@ -0,0 +1,72 @@
+package yacht_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/metrics"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestReconcileTimeout(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	// timeouts are retried with backoff regardless of the policy
+	h.Controller.WithReconcileTimeout(10 * time.Millisecond).
+		WithErrorPolicy(func(error) yacht.ErrorAction { return yacht.ErrorActionDrop })
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+
+	calls := h.Calls()
+	if len(calls) != 1 || calls[0].Err == nil {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	if rateLimited := h.Queue.RateLimited(); len(rateLimited) != 1 {
+		t.Fatalf("expected the timed out work item to be retried with backoff, got %v", rateLimited)
+	}
+}
+
+func TestReconcileTimeoutIgnoresSucceededHandlers(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+		// succeeded regardless of the deadline
+		<-ctx.Done()
+		return nil, nil
+	})
+	h.Controller.WithReconcileTimeout(10 * time.Millisecond)
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+
+	if len(h.Queue.RateLimited()) != 0 || len(h.Queue.Delayed()) != 0 {
+		t.Fatal("expected the succeeded work item not to be retried")
+	}
+}
+
+func TestStuckHandlerDetection(t *testing.T) {
+	registry := metrics.NewRegistry()
+	h := yachttest.NewHarness[string]("stuck", func(_ context.Context, key string) (*time.Duration, error) {
+		if key == "stuck" {
+			// ignore the cancellation
+			time.Sleep(200 * time.Millisecond)
+		}
+		return nil, nil
+	})
+	h.Controller.WithMetricsProvider(registry).
+		WithReconcileTimeout(10 * time.Millisecond).
+		WithStuckHandlerDetection(10 * time.Millisecond)
+	h.Add(namespace("stuck"))
+	h.Add(namespace("quick"))
+	h.ProcessAll(context.Background())
+
+	var sb strings.Builder
+	if _, err := registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if line := `yacht_reconcile_stuck_handlers_total{controller="stuck"} 1`; !strings.Contains(sb.String(), line) {
+		t.Fatalf("expected %q in output:\n%s", line, sb.String())
+	}
+}
@@ -0,0 +1,59 @@
+package yacht_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestTombstoneUnwrapped(t *testing.T) {
+	var filtered interface{}
+	var involved runtime.Object
+	h := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+		involved, _ = yacht.InvolvedObjectFromContext(ctx)
+		return nil, nil
+	})
+	h.Controller.WithEnqueueFilterFunc(func(oldObj, _ interface{}) (bool, error) {
+		filtered = oldObj
+		return true, nil
+	})
+	h.Delete(cache.DeletedFinalStateUnknown{Key: "a", Obj: namespace("a")})
+	h.ProcessAll(context.Background())
+
+	if ns, ok := filtered.(*corev1.Namespace); !ok || ns.Name != "a" {
+		t.Fatalf("expected the filter to get the deleted object, got %#v", filtered)
+	}
+	if ns, ok := involved.(*corev1.Namespace); !ok || ns.Name != "a" {
+		t.Fatalf("expected the deleted object to be the involved object, got %#v", involved)
+	}
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
+
+func TestTombstoneWithoutObject(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Delete(cache.DeletedFinalStateUnknown{Key: "default/a"})
+	h.ProcessAll(context.Background())
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"default/a"}) {
+		t.Fatalf("expected the tombstone to be keyed by its original key, got %v", keys)
+	}
+}
+
+func TestTombstoneMappedToOwners(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	handler := h.Controller.MappedResourceEventHandlerFuncs(yacht.EnqueueOwner[string](replicaSetGVK, true))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/pod", Obj: ownedPod()})
+	h.ProcessAll(context.Background())
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"default/controller"}) {
+		t.Fatalf("expected the tombstone to be mapped to its owner, got %v", keys)
+	}
+}
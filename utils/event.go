@@ -0,0 +1,28 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// NewEventRecorder creates an EventRecorder which writes events to the API server.
+// The scheme should recognize all the involved object types, e.g. scheme.Scheme from client-go for built-in types.
+func NewEventRecorder(client typedcorev1.EventsGetter, scheme *runtime.Scheme, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(4)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.Events("")})
+	return broadcaster.NewRecorder(scheme, corev1.EventSource{Component: component})
+}
+
+// InvolvedObject returns obj as the involved object of events. Same as DepthLogging, the object should carry
+// the object metadata, otherwise false is returned.
+func InvolvedObject(obj interface{}) (runtime.Object, bool) {
+	if _, ok := obj.(metav1.Object); !ok {
+		return nil, false
+	}
+	o, ok := obj.(runtime.Object)
+	return o, ok
+}
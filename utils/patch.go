@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// NewDynamicPatchFunc creates a function to patch objects of the given resource with a dynamic client,
+// which works for any resources including CRDs.
+func NewDynamicPatchFunc(client dynamic.Interface,
+	gvr schema.GroupVersionResource) func(ctx context.Context, obj metav1.Object, patchType types.PatchType, data []byte) error {
+	return func(ctx context.Context, obj metav1.Object, patchType types.PatchType, data []byte) error {
+		_, err := client.Resource(gvr).Namespace(obj.GetNamespace()).
+			Patch(ctx, obj.GetName(), patchType, data, metav1.PatchOptions{})
+		return err
+	}
+}
@@ -0,0 +1,71 @@
+package yacht
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WatchOptions configures how a watched resource enqueues work items
+type WatchOptions struct {
+	// ResyncPeriod is the resync period of the event handler. Zero means using the resync period of the informer.
+	ResyncPeriod time.Duration
+	// EventHandler handles the events of the watched resource.
+	// DefaultResourceEventHandlerFuncs of the controller will be used if not set.
+	EventHandler cache.ResourceEventHandler
+}
+
+// Watches registers the event handler to the informer and waits for the handler to receive the initial list of the
+// informer before starting workers. The informer should be started by its informer factory, or with
+// Manager.WithInformerFactories.
+func (c *TypedController[K]) Watches(informer cache.SharedInformer, opts *WatchOptions) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not add watches when controller %s is running", c.name))
+	}
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	handler := opts.EventHandler
+	if handler == nil {
+		handler = c.DefaultResourceEventHandlerFuncs()
+	}
+
+	var reg cache.ResourceEventHandlerRegistration
+	var err error
+	if opts.ResyncPeriod > 0 {
+		reg, err = informer.AddEventHandlerWithResyncPeriod(handler, opts.ResyncPeriod)
+	} else {
+		reg, err = informer.AddEventHandler(handler)
+	}
+	if err != nil {
+		panic(fmt.Errorf("failed to add event handler for controller %s: %v", c.name, err))
+	}
+
+	// the handler may not have received the initial list yet when the informer is synced
+	return c.WithCacheSynced(reg.HasSynced)
+}
+
+// WatchesResource creates an informer for the resource with the dynamic informer factory, which works for
+// arbitrary resources including CRDs. The informer factory will be started when the controller runs.
+func (c *TypedController[K]) WatchesResource(factory dynamicinformer.DynamicSharedInformerFactory,
+	gvr schema.GroupVersionResource, opts *WatchOptions) *TypedController[K] {
+	c.Watches(factory.ForResource(gvr).Informer(), opts)
+	c.informerFactories = append(c.informerFactories, factory)
+	return c
+}
+
+// WatchesGVK works like WatchesResource, while the resource is resolved from gvk with the RESTMapper,
+// e.g. restmapper.NewDeferredDiscoveryRESTMapper.
+func (c *TypedController[K]) WatchesGVK(factory dynamicinformer.DynamicSharedInformerFactory, mapper meta.RESTMapper,
+	gvk schema.GroupVersionKind, opts *WatchOptions) *TypedController[K] {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		panic(fmt.Errorf("failed to get the resource of %s for controller %s: %v", gvk, c.name, err))
+	}
+	return c.WatchesResource(factory, mapping.Resource, opts)
+}
@@ -0,0 +1,72 @@
+package yacht_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func TestWatchesWaitsForInitialList(t *testing.T) {
+	var objects []runtime.Object
+	for i := 0; i < 50; i++ {
+		objects = append(objects, namespace(fmt.Sprintf("ns-%d", i)))
+	}
+	h := yachttest.NewHarness[string]("test", succeed, objects...)
+	var enqueued atomic.Int32
+	h.Controller.
+		WithEnqueueFilterFunc(func(_, _ interface{}) (bool, error) {
+			// slow down the handler to fall behind the informer
+			time.Sleep(5 * time.Millisecond)
+			enqueued.Add(1)
+			return true, nil
+		}).
+		Watches(h.InformerFactory.Core().V1().Namespaces().Informer(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.InformerFactory.Start(ctx.Done())
+
+	stop := run(t, h)
+	defer stop()
+	if n := enqueued.Load(); n != 50 {
+		t.Fatalf("expected the initial list to be enqueued before starting workers, got %d", n)
+	}
+	eventually(t, "the work items to be processed", func() bool { return len(h.Calls()) == 50 })
+}
+
+func TestWatchesWithEventHandler(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed, namespace("a"))
+	h.Controller.Watches(h.InformerFactory.Core().V1().Namespaces().Informer(), &yacht.WatchOptions{
+		ResyncPeriod: time.Minute,
+		EventHandler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				h.Controller.EnqueueWithPriority(obj, yacht.PriorityHigh)
+			},
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.InformerFactory.Start(ctx.Done())
+
+	stop := run(t, h)
+	defer stop()
+	if _, err := h.Client.CoreV1().Namespaces().Create(ctx, namespace("b"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the work items to be processed", func() bool { return len(h.Calls()) == 2 })
+	keys := h.CalledKeys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+}
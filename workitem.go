@@ -0,0 +1,125 @@
+package yacht
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// WorkItemEvents describes the informer events coalesced into a work item since it was last processed
+type WorkItemEvents struct {
+	// Types are the distinct event types in the order of their first occurrences, e.g. [Added, Updated]
+	Types []cache.DeltaType
+	// Last is the type of the last event
+	Last cache.DeltaType
+	// Object is the last observed object, which is the final state of the object if Last is Deleted
+	Object interface{}
+}
+
+// Has checks whether any event of deltaType has been coalesced
+func (e *WorkItemEvents) Has(deltaType cache.DeltaType) bool {
+	return slices.Contains(e.Types, deltaType)
+}
+
+// Deleted checks whether the object has been deleted in the last event
+func (e *WorkItemEvents) Deleted() bool {
+	return e.Last == cache.Deleted
+}
+
+// merge appends the events in newer
+func (e *WorkItemEvents) merge(newer *WorkItemEvents) {
+	for _, deltaType := range newer.Types {
+		if !e.Has(deltaType) {
+			e.Types = append(e.Types, deltaType)
+		}
+	}
+	e.Last = newer.Last
+	e.Object = newer.Object
+}
+
+type workItemEventsKey struct{}
+
+// WorkItemEventsFromContext returns the events coalesced into the work item inside the handler. It is only
+// available with WithWorkItemEvents, and when the work item is enqueued by the default event handlers.
+func WorkItemEventsFromContext(ctx context.Context) (*WorkItemEvents, bool) {
+	events, ok := ctx.Value(workItemEventsKey{}).(*WorkItemEvents)
+	return events, ok
+}
+
+// WithWorkItemEvents tracks the events of the work items enqueued by DefaultResourceEventHandlerFuncs, which can
+// be retrieved inside the handler with WorkItemEventsFromContext. Work items are still deduplicated by keys, while
+// their events are coalesced. When a work item is requeued, its events are kept for the next try.
+func (c *TypedController[K]) WithWorkItemEvents() *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate workItemEvents when controller %s is running", c.name))
+	}
+
+	c.workItemEvents = &eventTracker[K]{items: map[K]*WorkItemEvents{}}
+	return c
+}
+
+// eventTracker records the coalesced events of the work items
+type eventTracker[K comparable] struct {
+	lock  sync.Mutex
+	items map[K]*WorkItemEvents
+}
+
+// track records an event of key
+func (t *eventTracker[K]) track(key K, deltaType cache.DeltaType, obj interface{}) {
+	events := &WorkItemEvents{Types: []cache.DeltaType{deltaType}, Last: deltaType, Object: obj}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if older, ok := t.items[key]; ok {
+		older.merge(events)
+		return
+	}
+	t.items[key] = events
+}
+
+// take removes the events of key
+func (t *eventTracker[K]) take(key K) (*WorkItemEvents, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	events, ok := t.items[key]
+	delete(t.items, key)
+	return events, ok
+}
+
+// restore puts back the events of key, which happened before the events tracked since then
+func (t *eventTracker[K]) restore(key K, events *WorkItemEvents) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if newer, ok := t.items[key]; ok {
+		events.merge(newer)
+	}
+	t.items[key] = events
+}
+
+// trackEvent records an event of key if WithWorkItemEvents is set
+func (c *TypedController[K]) trackEvent(key K, deltaType cache.DeltaType, obj interface{}) {
+	if c.workItemEvents != nil {
+		c.workItemEvents.track(key, deltaType, obj)
+	}
+}
+
+// workItemEventsContext injects the events of key into ctx
+func (c *TypedController[K]) workItemEventsContext(ctx context.Context, key K) (context.Context, *WorkItemEvents) {
+	if c.workItemEvents == nil {
+		return ctx, nil
+	}
+	events, ok := c.workItemEvents.take(key)
+	if !ok {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, workItemEventsKey{}, events), events
+}
+
+// keepEvents puts back the events of a requeued work item
+func (c *TypedController[K]) keepEvents(key K, events *WorkItemEvents) {
+	if events != nil {
+		c.workItemEvents.restore(key, events)
+	}
+}
@@ -0,0 +1,109 @@
+package yacht_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+// newEventsHarness creates a Harness tracking the work item events, which are recorded on each call, or nil if not
+// available. The handler fails when fail returns true for the number of calls.
+func newEventsHarness(fail func(calls int) bool) (*yachttest.Harness[string], *[]*yacht.WorkItemEvents) {
+	var recorded []*yacht.WorkItemEvents
+	h := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+		events, _ := yacht.WorkItemEventsFromContext(ctx)
+		recorded = append(recorded, events)
+		if fail != nil && fail(len(recorded)) {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	h.Controller.WithWorkItemEvents()
+	return h, &recorded
+}
+
+func labelled(name, value string) *corev1.Namespace {
+	ns := namespace(name)
+	ns.Labels = map[string]string{"value": value}
+	return ns
+}
+
+func TestWorkItemEventsCoalesced(t *testing.T) {
+	h, recorded := newEventsHarness(nil)
+	h.Add(labelled("a", "1"))
+	h.Update(labelled("a", "1"), labelled("a", "2"))
+	h.Update(labelled("a", "2"), labelled("a", "3"))
+	h.ProcessAll(context.Background())
+
+	if len(*recorded) != 1 || (*recorded)[0] == nil {
+		t.Fatalf("expected the events of a single work item, got %v", *recorded)
+	}
+	events := (*recorded)[0]
+	if expected := []cache.DeltaType{cache.Added, cache.Updated}; !reflect.DeepEqual(events.Types, expected) {
+		t.Fatalf("expected event types %v, got %v", expected, events.Types)
+	}
+	if events.Last != cache.Updated || events.Deleted() || !events.Has(cache.Added) || events.Has(cache.Deleted) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if ns := events.Object.(*corev1.Namespace); ns.Labels["value"] != "3" {
+		t.Fatalf("expected the last observed object, got %v", ns)
+	}
+
+	// the events have been taken by the handler
+	h.Delete(labelled("a", "3"))
+	h.ProcessAll(context.Background())
+	if events = (*recorded)[1]; !reflect.DeepEqual(events.Types, []cache.DeltaType{cache.Deleted}) || !events.Deleted() {
+		t.Fatalf("expected only the deletion, got %+v", events)
+	}
+}
+
+func TestWorkItemEventsKeptOnRequeue(t *testing.T) {
+	h, recorded := newEventsHarness(func(calls int) bool { return calls == 1 })
+	h.Add(labelled("a", "1"))
+	h.ProcessAll(context.Background())
+	h.Delete(labelled("a", "1"))
+	h.Flush()
+	h.ProcessAll(context.Background())
+
+	if len(*recorded) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(*recorded))
+	}
+	events := (*recorded)[1]
+	if expected := []cache.DeltaType{cache.Added, cache.Deleted}; !reflect.DeepEqual(events.Types, expected) {
+		t.Fatalf("expected the events before the failure to be kept, got %v", events.Types)
+	}
+	if !events.Deleted() {
+		t.Fatalf("expected the last event to be the deletion, got %+v", events)
+	}
+}
+
+func TestWorkItemEventsUnavailable(t *testing.T) {
+	h, recorded := newEventsHarness(nil)
+	h.Controller.Enqueue(namespace("a"))
+	h.Controller.MappedResourceEventHandlerFuncs(yacht.EnqueueMapped(func(_ interface{}) []string {
+		return []string{"b"}
+	})).OnAdd(namespace("c"), false)
+	h.ProcessAll(context.Background())
+	if !reflect.DeepEqual(*recorded, []*yacht.WorkItemEvents{nil, nil}) {
+		t.Fatalf("expected no events outside the default event handlers, got %v", *recorded)
+	}
+
+	var available bool
+	plain := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+		_, available = yacht.WorkItemEventsFromContext(ctx)
+		return nil, nil
+	})
+	plain.Add(namespace("a"))
+	plain.ProcessAll(context.Background())
+	if available {
+		t.Fatal("expected no events without WithWorkItemEvents")
+	}
+}
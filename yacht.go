@@ -4,65 +4,169 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/leaderelection"
 	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	utilpointer "k8s.io/utils/pointer"
 
+	"github.com/dixudx/yacht/metrics"
 	"github.com/dixudx/yacht/utils"
 )
 
-type Controller struct {
+// TypedController processes work items of type K off a rate limited work queue
+type TypedController[K comparable] struct {
 	// name is the name of this controller
 	name string
 	// workers indicates the number of workers
 	workers *int
-	// enqueueFunc defines the function to enqueue the work item
-	enqueueFunc EnqueueFunc
+	// workersLock guards workers and pool, which can be changed while running
+	workersLock sync.Mutex
+	// pool runs the workers while running
+	pool *workerPool[K]
+	// autoscaling scales the workers based on the queue depth and latency
+	autoscaling *AutoscalingOptions
+	// enqueuedAt records the time when each key is enqueued to measure the latency when autoscaling
+	enqueuedAt sync.Map
+	latency    latencyTracker
+	// busyWorkers records the number of workers that are handling work items
+	busyWorkers atomic.Int32
+	// enqueueKeysFunc defines the function to enqueue the work items, which may produce multiple keys
+	enqueueKeysFunc TypedEnqueueKeysFunc[interface{}, K]
 	// enqueueFilterFunc defines the filter function before enqueueing the work item
 	enqueueFilterFunc EnqueueFilterFunc
-	// queue is a rate limited work queue.
-	queue workqueue.RateLimitingInterface
+	// queue is a rate limited work queue, which is rebuilt on restart.
+	queue *restartableQueue[K]
 	// informersSynced records a group of cacheSyncs
 	// The workers will not start working before all the caches are synced successfully
 	informersSynced []cache.InformerSynced
-	// handlerContextFunc defines the handler to process the work item
-	handlerContextFunc HandlerContextFunc
+	// informerFactories records the informer factories of the watched informers, which will be started
+	// before waiting for caches to sync
+	informerFactories []InformerFactory
+	// reconcileFunc defines the handler to process the work item
+	reconcileFunc TypedReconcileFunc[K]
 	// le specifies the LeaderElector to use
 	le *leaderelection.LeaderElector
+	// metricsProvider creates the metrics to record work processing
+	metricsProvider metrics.Provider
+	// reconcileMetrics caches the reconcile metrics created by metricsProvider
+	reconcileMetrics *reconcileMetrics
+	// recorder records events on the involved objects for reconcile outcomes
+	recorder record.EventRecorder
+	// eventOptions configures which reconcile outcomes will be recorded as events
+	eventOptions EventOptions
+	// involvedObjects records the last enqueued object of each key
+	involvedObjects sync.Map
+	// finalizer manages a finalizer on the objects
+	finalizer *TypedFinalizer[K]
+	// errorPolicy classifies the errors returned from the handler
+	errorPolicy ErrorPolicy
+	// failures counts the consecutive failures of the work items
+	failures failureTracker[K]
+	// maxRetries limits the number of rate limited retries of a failed work item
+	maxRetries int
+	// deadLetterFunc handles the work items exceeding maxRetries
+	deadLetterFunc TypedDeadLetterFunc[K]
+	// reconcileTimeout bounds each call of the handler
+	reconcileTimeout time.Duration
+	// detectStuckHandlers reports handlers running longer than stuckGracePeriod past reconcileTimeout
+	detectStuckHandlers bool
+	stuckGracePeriod    time.Duration
+	// maxPanics crashes the process once the handler has panicked so many times
+	maxPanics int64
+	panics    atomic.Int64
+	// eventHandlerOptions filters the update events in the default event handlers
+	eventHandlerOptions EventHandlerOptions
+	// workItemEvents tracks the events coalesced into the work items
+	workItemEvents *eventTracker[K]
+	// keyLock is shared with other controllers to avoid processing the same key in parallel
+	keyLock *KeyLock
+	// fatalCh receives the escalated error which stops the controller
+	fatalCh chan error
+	// stopLeading releases the lease when the controller stops on a fatal error
+	stopLeading context.CancelFunc
 
 	// runFlag indicates whether the workers start working
 	runFlag bool
-
-	once sync.Once
+	// cacheSynced indicates whether all the informersSynced have completed
+	cacheSynced atomic.Bool
+	// workersStarted indicates whether the workers have been launched
+	workersStarted atomic.Bool
+	// shuttingDown indicates whether the workers are being stopped
+	shuttingDown atomic.Bool
+	// drainTimeout is the maximum duration to wait for in-flight work items when shutting down
+	drainTimeout time.Duration
+	// stopCh is closed when the controller is asked to stop, which may come earlier than the cancellation of the
+	// context passed to run, e.g. the lease is held until all the in-flight work items are drained.
+	stopCh <-chan struct{}
+	// knownKeys records all the enqueued keys until their objects are deleted, which are enqueued again on restart
+	// or re-election
+	knownKeys keySet[K]
+	// runs counts the runs, including the leader terms
+	runs int
+	// exitOnLeaseLoss exits the process once the lease is lost
+	exitOnLeaseLoss bool
+	// leaderHooks are called on the leadership transitions
+	leaderHooks []LeaderHooks
+	// leading indicates whether the lease has been acquired
+	leading atomic.Bool
+	// informerStopCh stops the informers, which keep running when the controller is stopped by Stop, since
+	// informers can not be restarted
+	informerStopCh <-chan struct{}
+	// running tracks whether the workers are running
+	running runGuard
+
+	// lifecycle guards cancelRun, runDone and resumeCh
+	lifecycle sync.Mutex
+	// cancelRun stops the current Run
+	cancelRun context.CancelFunc
+	// runDone is closed when the current Run returns
+	runDone chan struct{}
+	// resumeCh is closed on Resume, which is nil unless paused
+	resumeCh chan struct{}
 }
 
+// Controller processes work items of any type, which keeps compatible with the untyped handlers
+type Controller = TypedController[interface{}]
+
 var _ Interface = &Controller{}
+var _ TypedInterface[string] = &TypedController[string]{}
+var _ Runnable = &Controller{}
 
 // NewController creates a new Controller
 func NewController(name string) *Controller {
-	return &Controller{
-		name:        name,
-		workers:     utilpointer.Int(2),
-		enqueueFunc: DefaultEnqueueFunc,
-		queue: workqueue.NewRateLimitingQueueWithConfig(
-			workqueue.DefaultControllerRateLimiter(),
-			workqueue.RateLimitingQueueConfig{
-				Name: name,
-			}),
-		informersSynced: []cache.InformerSynced{},
+	return NewTypedController[interface{}](name)
+}
+
+// NewTypedController creates a new TypedController. The default enqueueFunc generates keys in the format of
+// <namespace>/<name>, which only works when K is string or interface{}. Otherwise, use WithEnqueueFunc to set one.
+func NewTypedController[K comparable](name string) *TypedController[K] {
+	newQueue := func() TypedRateLimitingInterface[K] {
+		return newDefaultQueue[K](name)
+	}
+	return &TypedController[K]{
+		name:             name,
+		workers:          utilpointer.Int(2),
+		enqueueKeysFunc:  singleKey(defaultTypedEnqueueFunc[K]),
+		queue:            newRestartableQueue(newQueue(), newQueue),
+		informersSynced:  []cache.InformerSynced{},
+		metricsProvider:  metrics.NoopProvider,
+		reconcileMetrics: newReconcileMetrics(metrics.NoopProvider, name),
+		errorPolicy:      DefaultErrorPolicy,
+		fatalCh:          make(chan error, 1),
 	}
 }
 
 // WithWorkers sets the number of workers to process work items off work queue
-func (c *Controller) WithWorkers(workers int) *Controller {
+func (c *TypedController[K]) WithWorkers(workers int) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate workers when controller %s is running", c.name))
 	}
@@ -74,18 +178,50 @@ func (c *Controller) WithWorkers(workers int) *Controller {
 	return c
 }
 
-// WithQueue replaces the default queue with the desired one to store work items.
-func (c *Controller) WithQueue(queue workqueue.RateLimitingInterface) *Controller {
+// WithQueue replaces the default queue with the desired one to store work items. Since the queue can not be
+// reused once shut down, the controller can neither be restarted after being stopped, nor work with leader
+// election, which starts over on each leader term. Use WithQueueFunc instead in those cases.
+func (c *TypedController[K]) WithQueue(queue workqueue.RateLimitingInterface) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate queue when controller %s is running", c.name))
 	}
 
-	c.queue = queue
+	c.queue = newRestartableQueue(NewTypedRateLimitingQueue[K](queue), nil)
 	return c
 }
 
-// WithEnqueueFilterFunc sets customize enqueueFilterFunc
-func (c *Controller) WithEnqueueFilterFunc(enqueueFilterFunc EnqueueFilterFunc) *Controller {
+// WithDrainTimeout enables graceful shutdown. When the controller is asked to stop, it stops processing new work
+// items and waits up to timeout for in-flight work items to be done before the context passed to the handler is
+// cancelled. When leader election is enabled, the lease will not be released until the draining finishes.
+func (c *TypedController[K]) WithDrainTimeout(timeout time.Duration) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate drainTimeout when controller %s is running", c.name))
+	}
+	if timeout < 0 {
+		panic(fmt.Errorf("can not set negative drainTimeout %v", timeout))
+	}
+
+	c.drainTimeout = timeout
+	return c
+}
+
+// WithMetricsProvider sets the metrics provider to record reconcile total/errors/requeues/duration.
+// Use metrics.DefaultRegistry to serve the metrics in the Prometheus text exposition format.
+func (c *TypedController[K]) WithMetricsProvider(provider metrics.Provider) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate metricsProvider when controller %s is running", c.name))
+	}
+
+	if provider != nil {
+		c.metricsProvider = provider
+		c.reconcileMetrics = newReconcileMetrics(provider, c.name)
+	}
+	return c
+}
+
+// WithEnqueueFilterFunc sets customize enqueueFilterFunc.
+// Use FilterFuncFor to convert a filter working on a specific object type.
+func (c *TypedController[K]) WithEnqueueFilterFunc(enqueueFilterFunc EnqueueFilterFunc) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate enqueueFilterFunc when controller %s is running", c.name))
 	}
@@ -94,39 +230,88 @@ func (c *Controller) WithEnqueueFilterFunc(enqueueFilterFunc EnqueueFilterFunc)
 	return c
 }
 
-// WithEnqueueFunc sets customize enqueueFunc
-func (c *Controller) WithEnqueueFunc(enqueueFunc EnqueueFunc) *Controller {
+// WithEnqueueFunc sets customize enqueueFunc.
+// Use EnqueueFuncFor to convert an enqueueFunc working on a specific object type.
+func (c *TypedController[K]) WithEnqueueFunc(enqueueFunc TypedEnqueueFunc[interface{}, K]) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate enqueueFunc when controller %s is running", c.name))
 	}
 
 	if enqueueFunc != nil {
-		c.enqueueFunc = enqueueFunc
+		c.enqueueKeysFunc = singleKey(enqueueFunc)
+	}
+	return c
+}
+
+// WithEnqueueKeysFunc sets customize enqueueKeysFunc, which converts an object into multiple keys.
+// This replaces the enqueueFunc.
+func (c *TypedController[K]) WithEnqueueKeysFunc(enqueueKeysFunc TypedEnqueueKeysFunc[interface{}, K]) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate enqueueKeysFunc when controller %s is running", c.name))
+	}
+
+	if enqueueKeysFunc != nil {
+		c.enqueueKeysFunc = enqueueKeysFunc
 	}
 	return c
 }
 
-func (c *Controller) DefaultResourceEventHandlerFuncs() cache.ResourceEventHandlerFuncs {
+// DefaultResourceEventHandlerFuncs enqueues the keys of the objects with the enqueueFunc. Like all the enqueued keys,
+// they are remembered and enqueued again when the controller is restarted or re-elected, since the events are
+// dropped while the controller is not running. The keys are forgotten once their objects are deleted.
+func (c *TypedController[K]) DefaultResourceEventHandlerFuncs() cache.ResourceEventHandlerFuncs {
+	return c.resourceEventHandlerFuncs(func(obj interface{}, deltaType cache.DeltaType, add func(key K)) {
+		c.enqueueKeys(obj, c.enqueueKeysFunc, true, func(key K) {
+			c.trackEvent(key, deltaType, obj)
+			if deltaType == cache.Deleted {
+				c.knownKeys.delete(key)
+			}
+			add(key)
+		})
+	})
+}
+
+// MappedResourceEventHandlerFuncs works like DefaultResourceEventHandlerFuncs, but maps the objects into keys with
+// mapFunc instead of the enqueueFunc, e.g. mapping secondary resources to their owners with EnqueueOwner.
+func (c *TypedController[K]) MappedResourceEventHandlerFuncs(mapFunc TypedEnqueueKeysFunc[interface{}, K]) cache.ResourceEventHandlerFuncs {
+	return c.resourceEventHandlerFuncs(func(obj interface{}, _ cache.DeltaType, add func(key K)) {
+		c.enqueueKeys(obj, mapFunc, false, add)
+	})
+}
+
+// resourceEventHandlerFuncs enqueues the objects with PriorityDefault for real events, while the update events are
+// filtered and periodic resyncs are enqueued as configured by the eventHandlerOptions. Tombstones of deleted
+// objects are unwrapped, so that the filters, enqueueFuncs and handlers get the last known state of the objects.
+func (c *TypedController[K]) resourceEventHandlerFuncs(enqueue func(obj interface{}, deltaType cache.DeltaType, add func(key K))) cache.ResourceEventHandlerFuncs {
 	return cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if c.applyEnqueueFilterFunc(nil, obj, cache.Added) {
-				c.Enqueue(obj)
+				enqueue(obj, cache.Added, c.addWithPriority(PriorityDefault))
 			}
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
+			add, ok := c.updateAddFunc(oldObj, newObj)
+			if !ok {
+				utils.DepthLogging(nil, "info", fmt.Sprintf("[%s] ignore resource", cache.Updated), newObj)
+				return
+			}
 			if c.applyEnqueueFilterFunc(oldObj, newObj, cache.Updated) {
-				c.Enqueue(newObj)
+				enqueue(newObj, cache.Updated, add)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
+			// the informer may miss the deletion and only deliver the last known state of the object
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok && tombstone.Obj != nil {
+				obj = tombstone.Obj
+			}
 			if c.applyEnqueueFilterFunc(obj, nil, cache.Deleted) {
-				c.Enqueue(obj)
+				enqueue(obj, cache.Deleted, c.addWithPriority(PriorityDefault))
 			}
 		},
 	}
 }
 
-func (c *Controller) applyEnqueueFilterFunc(oldObj, newObj interface{}, operation cache.DeltaType) bool {
+func (c *TypedController[K]) applyEnqueueFilterFunc(oldObj, newObj interface{}, operation cache.DeltaType) bool {
 	if c.enqueueFilterFunc == nil {
 		obj := oldObj
 		if obj == nil {
@@ -170,70 +355,69 @@ func (c *Controller) applyEnqueueFilterFunc(oldObj, newObj interface{}, operatio
 
 // WithHandlerFunc sets a handler function to process the work item off the work queue
 // Deprecated: Use WithHandlerContextFunc instead.
-func (c *Controller) WithHandlerFunc(handlerFunc HandlerFunc) *Controller {
+func (c *TypedController[K]) WithHandlerFunc(handlerFunc HandlerFunc) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate handlerContextFunc when controller %s is running", c.name))
 	}
 
 	if handlerFunc != nil {
-		c.handlerContextFunc = func(ctx context.Context, key interface{}) (requeueAfter *time.Duration, err error) {
+		c.reconcileFunc = ReconcileFuncFor(func(ctx context.Context, key K) (requeueAfter *time.Duration, err error) {
 			select {
 			case <-ctx.Done():
 				return
 			default:
 				return handlerFunc(key)
 			}
-		}
+		})
 	}
 	return c
 }
 
 // WithHandlerContextFunc sets a handler function to process the work item off the work queue
-func (c *Controller) WithHandlerContextFunc(handlerContextFunc HandlerContextFunc) *Controller {
+func (c *TypedController[K]) WithHandlerContextFunc(handlerContextFunc TypedHandlerFunc[K]) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate handlerContextFunc when controller %s is running", c.name))
 	}
 
 	if handlerContextFunc != nil {
-		c.handlerContextFunc = handlerContextFunc
+		c.reconcileFunc = ReconcileFuncFor(handlerContextFunc)
+	}
+	return c
+}
+
+// WithReconcileFunc sets a handler function returning a Result to process the work item off the work queue
+func (c *TypedController[K]) WithReconcileFunc(reconcileFunc TypedReconcileFunc[K]) *TypedController[K] {
+	if c.runFlag {
+		panic(fmt.Errorf("can not mutate reconcileFunc when controller %s is running", c.name))
+	}
+
+	if reconcileFunc != nil {
+		c.reconcileFunc = reconcileFunc
 	}
 	return c
 }
 
 // WithLeaderElection uses leader election to get the lock
-func (c *Controller) WithLeaderElection(leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration) *Controller {
+func (c *TypedController[K]) WithLeaderElection(leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration) *TypedController[K] {
 	if c.runFlag {
 		panic(fmt.Errorf("can not mutate leaderElection when controller %s is running", c.name))
 	}
 
-	lec := leaderelection.LeaderElectionConfig{
-		Lock: leaseLock,
-		// IMPORTANT: you MUST ensure that any code you have that is protected by the lease must terminate **before**
-		// you call cancel. Otherwise, you could have a background loop still running and another process could
-		// get elected before your background loop finished, violating the stated goal of the lease.
-		ReleaseOnCancel: true,
-		LeaseDuration:   leaseDuration,
-		RenewDeadline:   renewDeadline,
-		RetryPeriod:     retryPeriod,
-		Callbacks: leaderelection.LeaderCallbacks{
+	le, err := newLeaderElector(fmt.Sprintf("controller %s", c.name), leaseLock, leaseDuration, renewDeadline, retryPeriod,
+		leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
-				c.run(ctx)
-			},
-			OnStoppedLeading: func() {
-				klog.Errorf("leader election got lost for controller %s", c.name)
-			},
-			OnNewLeader: func(identity string) {
-				// gets notified when new leader is elected
-				if identity == leaseLock.Identity() {
-					// I just got the lock
-					return
+				c.startedLeading(ctx)
+				if err := c.run(ctx, ctx.Done()); err != nil {
+					klog.Error(err)
+					// give up the lease, since the controller can not work
+					if c.stopLeading != nil {
+						c.stopLeading()
+					}
 				}
-				klog.Infof("new leader %s is elected for controller %s", identity, c.name)
 			},
-		},
-	}
-
-	le, err := leaderelection.NewLeaderElector(lec)
+			OnStoppedLeading: c.stoppedLeading,
+			OnNewLeader:      c.newLeader,
+		})
 	if err != nil {
 		panic(fmt.Errorf("failed to create a LeaderElector for controller %s: %v", c.name, err))
 	}
@@ -242,103 +426,563 @@ func (c *Controller) WithLeaderElection(leaseLock rl.Interface, leaseDuration, r
 }
 
 // WithCacheSynced sets all the resource cacheSynced
-func (c *Controller) WithCacheSynced(informersSynced ...cache.InformerSynced) *Controller {
+func (c *TypedController[K]) WithCacheSynced(informersSynced ...cache.InformerSynced) *TypedController[K] {
 	c.informersSynced = append(c.informersSynced, informersSynced...)
 	return c
 }
 
-// Enqueue takes an object and converts it into a key (could be a string, or a struct) which is then put onto the
-// work queue.
-func (c *Controller) Enqueue(obj interface{}) {
-	key, err := c.enqueueFunc(obj)
+// Enqueue takes an object and converts it into one or more keys (could be a string, or a struct) which are then put
+// onto the work queue. The keys are enqueued again when the controller is restarted or re-elected.
+func (c *TypedController[K]) Enqueue(obj interface{}) {
+	c.enqueueKeys(obj, c.enqueueKeysFunc, true, c.addWithPriority(PriorityDefault))
+}
+
+// enqueueKeys puts all the keys of obj onto the work queue. The object will be remembered as the involved object
+// of the keys only when it is the primary resource of the keys.
+func (c *TypedController[K]) enqueueKeys(obj interface{}, enqueueKeysFunc TypedEnqueueKeysFunc[interface{}, K], primary bool,
+	add func(key K)) {
+	keys, err := enqueueKeysFunc(obj)
 	if err != nil {
 		utilruntime.HandleError(err)
 		return
 	}
-	c.queue.Add(key)
+	for _, key := range keys {
+		if primary {
+			c.rememberObject(key, obj)
+		}
+		c.knownKeys.insert(key)
+		c.markEnqueued(key)
+		add(key)
+	}
 }
 
-// Run will start multiple workers to process work items from work queue. It will block until ctx is closed.
-func (c *Controller) Run(ctx context.Context) {
+// Run will start multiple workers to process work items from work queue. It will block until ctx is closed or
+// Stop is called. A stopped controller can be started again with Run.
+func (c *TypedController[K]) Run(ctx context.Context) {
 	defer utilruntime.HandleCrash()
+
+	if err := c.validate(); err != nil {
+		panic(err)
+	}
+
+	runCtx, ok := c.start(ctx)
+	if !ok {
+		return
+	}
+	defer c.finish()
 	defer c.queue.ShutDown()
 
-	if c.handlerContextFunc == nil {
-		panic(fmt.Errorf("please set handlerContextFunc for controller %s", c.name))
+	c.informerStopCh = ctx.Done()
+	c.stopCh = runCtx.Done()
+	if c.le != nil {
+		// the lease should be released only after the workers stop
+		leCtx, cancel := leaderContext(runCtx, c.running.wait)
+		defer cancel()
+		c.stopLeading = cancel
+		wait.UntilWithContext(leCtx, c.le.Run, time.Duration(0))
+		return
 	}
+	if err := c.run(runCtx, nil); err != nil {
+		klog.Error(err)
+	}
+}
 
-	c.once.Do(func() {
-		if c.le != nil {
-			wait.UntilWithContext(ctx, c.le.Run, time.Duration(0))
-			return
-		}
-		c.run(ctx)
-	})
+// validate checks whether the controller is ready to run
+func (c *TypedController[K]) validate() error {
+	if c.reconcileFunc == nil {
+		return fmt.Errorf("please set handlerContextFunc or reconcileFunc for controller %s", c.name)
+	}
+	if c.le != nil && !c.queue.rebuildable() {
+		return fmt.Errorf("please set the queue with WithQueueFunc instead of WithQueue for controller %s, "+
+			"since leader election rebuilds the queue on each leader term", c.name)
+	}
+	return nil
 }
 
-func (c *Controller) run(ctx context.Context) {
+func (c *TypedController[K]) controllerName() string {
+	return c.name
+}
+
+func (c *TypedController[K]) hasLeaderElection() bool {
+	return c.le != nil
+}
+
+func (c *TypedController[K]) setStopCh(stopCh <-chan struct{}) {
+	c.stopCh = stopCh
+}
+
+func (c *TypedController[K]) shutDownQueue() {
+	c.queue.ShutDown()
+}
+
+func (c *TypedController[K]) exitsOnLeaseLoss() bool {
+	return c.exitOnLeaseLoss
+}
+
+// run runs the workers until ctx is closed. leaseLost is closed once the lease is lost, which is nil without leader
+// election.
+func (c *TypedController[K]) run(ctx context.Context, leaseLost <-chan struct{}) error {
 	klog.Infof("starting controller %s", c.name)
 	defer klog.Infof("shutting down controller %s", c.name)
 	c.runFlag = true
+	// join the workers of the last term, which may still be stopping after the lease is lost
+	c.running.wait()
+	defer c.running.enter()()
+	defer c.cacheSynced.Store(false)
+	defer c.workersStarted.Store(false)
+
+	// discard the fatal error reported in the last run
+	select {
+	case <-c.fatalCh:
+	default:
+	}
+
+	// the queue has been shut down in the last run
+	if err := c.queue.rebuild(); err != nil {
+		return fmt.Errorf("failed to restart controller %s: %v", c.name, err)
+	}
+
+	// informers can not be restarted once stopped, so they keep running across leader terms and restarts
+	informerStopCh := c.informerStopCh
+	if informerStopCh == nil {
+		informerStopCh = c.stopCh
+	}
+	if informerStopCh == nil {
+		informerStopCh = ctx.Done()
+	}
+	for _, f := range c.informerFactories {
+		f.Start(informerStopCh)
+	}
 
 	// Wait for all the caches to be synced before starting workers
 	if !cache.WaitForNamedCacheSync(c.name, ctx.Done(), c.informersSynced...) {
-		return
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to wait for caches to sync for controller %s", c.name)
+	}
+	c.cacheSynced.Store(true)
+	// the events delivered while not running have been dropped with the old queue
+	if c.runs > 0 {
+		c.enqueueAll()
 	}
+	c.runs++
 
-	klog.V(4).Infof("starting %d workers for controller %s", *c.workers, c.name)
+	// In-flight work items should be able to finish before the drain timeout, so the context passed to
+	// the handlers will be cancelled later than ctx.
+	workerCtx, cancelWorkers := context.WithCancel(ctx)
+	if c.drainTimeout > 0 {
+		workerCtx, cancelWorkers = context.WithCancel(context.WithoutCancel(ctx))
+	}
+	defer cancelWorkers()
+
+	klog.V(4).Infof("starting %d workers for controller %s", c.numWorkers(), c.name)
 	// Launch workers to process work items from queue
-	for i := 0; i < *c.workers; i++ {
-		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	pool := c.startWorkers(workerCtx)
+	c.workersStarted.Store(true)
+
+	var err error
+	var lost bool
+	select {
+	case <-ctx.Done():
+		lost = c.leaseLost(leaseLost)
+	case <-c.stopCh:
+	case err = <-c.fatalCh:
+	}
+	c.shuttingDown.Store(true)
+	defer c.shuttingDown.Store(false)
+	c.stopWorkers()
+	if lost {
+		// the work items must not be processed any more without the lease
+		cancelWorkers()
+	}
+	c.shutdown(cancelWorkers, pool)
+	klog.V(4).Infof("stopped workers for controller %s", c.name)
+	if lost && c.le != nil {
+		// the manager takes care of its own lease
+		c.onLeaseLost()
+	}
+	return err
+}
+
+// shutdown stops the queue and the workers. With a drain timeout, it waits for in-flight work items up to the
+// timeout before cancelling the workers. It always returns after all the workers exit, so that the lease is never
+// released while any handler is still running.
+func (c *TypedController[K]) shutdown(cancelWorkers context.CancelFunc, pool *workerPool[K]) {
+	defer pool.wait()
+	if c.drainTimeout == 0 {
+		cancelWorkers()
+		c.queue.ShutDown()
+		return
 	}
 
-	<-ctx.Done()
-	klog.V(4).Infof("stopped %d workers for controller %s", *c.workers, c.name)
+	klog.V(4).Infof("draining in-flight work items for controller %s with timeout %v", c.name, c.drainTimeout)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		c.queue.ShutDownWithDrain()
+	}()
+
+	timer := time.NewTimer(c.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		cancelWorkers()
+	case <-timer.C:
+		cancelWorkers()
+		// stop waiting for in-flight work items
+		c.queue.ShutDown()
+		klog.Warningf("timed out after %v waiting for in-flight work items for controller %s, cancelling them",
+			c.drainTimeout, c.name)
+	}
 }
 
-// runWorker starts an infinite loop on processing the work item until the work queue is shut down.
-func (c *Controller) runWorker(ctx context.Context) {
-	for c.processNextWorkItem(ctx) {
+// dispatch reads the work items from the work queue and hands them out to the idle workers of pool, until the work
+// queue is shut down or the pool is closed.
+func (c *TypedController[K]) dispatch(ctx context.Context, pool *workerPool[K]) {
+	for {
+		worker, ok := pool.nextIdle()
+		if !ok {
+			return
+		}
+		item, ok := c.nextWorkItem(ctx)
+		if !ok {
+			worker.cancel()
+			return
+		}
+		if ctx.Err() != nil {
+			// the pool is closed while waiting, so nobody will process it
+			c.queue.Done(item)
+			worker.cancel()
+			return
+		}
+		if !worker.hand(item) {
+			// the worker has retired while waiting, so put it back for the other workers
+			c.queue.Add(item)
+			c.queue.Done(item)
+		}
 	}
 }
 
-// processNextWorkItem reads a single work item from the work queue
-func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+// runWorker starts an infinite loop on processing the work items handed out by pool until the pool is closed or
+// the worker is retired.
+func (c *TypedController[K]) runWorker(ctx context.Context, pool *workerPool[K]) {
+	for {
+		item, ok := pool.next()
+		if !ok {
+			return
+		}
+		c.processWorkItem(ctx, item)
+	}
+}
+
+// ProcessNextWorkItem reads and processes a single work item from the work queue synchronously. It blocks until an
+// item is available and returns false when the queue is shut down. This is mainly used to drive the controller
+// step by step in tests, while Run launches workers to do this continuously.
+func (c *TypedController[K]) ProcessNextWorkItem(ctx context.Context) bool {
+	return c.processNextWorkItem(ctx)
+}
+
+// processNextWorkItem reads and processes a single work item from the work queue
+func (c *TypedController[K]) processNextWorkItem(ctx context.Context) bool {
+	item, ok := c.nextWorkItem(ctx)
+	if !ok {
+		return false
+	}
+	c.processWorkItem(ctx, item)
+	return true
+}
+
+// nextWorkItem reads a single work item from the work queue. It returns false when the work queue is shut down.
+func (c *TypedController[K]) nextWorkItem(ctx context.Context) (K, bool) {
+	var zero K
+	// stop picking up new work items when shutting down
+	if c.queue.ShuttingDown() || !c.waitForResume(ctx) {
+		return zero, false
+	}
+
 	item, quit := c.queue.Get()
 	if quit {
-		return false
+		return zero, false
 	}
-	defer c.queue.Done(item)
+	return item, true
+}
 
-	requeueAfter, err := c.handlerContextFunc(ctx, item)
+// processWorkItem processes a single work item read from the work queue
+func (c *TypedController[K]) processWorkItem(ctx context.Context, item K) {
+	defer c.queue.Done(item)
+	if c.Paused() {
+		// put it back, which will be picked up after resuming
+		c.queue.Add(item)
+		return
+	}
+	unlock, ok := c.lockKey(item)
+	if !ok {
+		return
+	}
+	defer unlock()
+	c.observeDequeued(item)
+	c.busyWorkers.Add(1)
+	defer c.busyWorkers.Add(-1)
+
+	start := time.Now()
+	ctx, involvedObject := c.eventContext(ctx, item)
+	ctx, events := c.workItemEventsContext(ctx, item)
+	result, err := c.reconcileWithRecover(ctx, item)
+	var requeued bool
 	if err == nil {
+		c.failures.forget(item)
+	}
+	switch {
+	case err != nil:
+		requeued = c.handleError(ctx, item, involvedObject, result, err, start)
+	case result.Skip:
+		c.forgetObject(item, involvedObject)
+		c.recordMetrics(start, metrics.ResultSkip)
+	case result.RequeueAfter > 0:
+		// Sometimes we may want to re-visit this object after a while.
+		// Put the item back on the work queue with delay.
 		c.queue.Forget(item)
-		if requeueAfter != nil {
-			// Sometimes we may want to re-visit this object after a while.
-			// Put the item back on the work queue with delay.
-			c.queue.AddAfter(item, *requeueAfter)
-		}
-		return true
+		c.queue.AddAfter(item, result.RequeueAfter)
+		c.recordEvent(involvedObject, result, nil)
+		c.recordMetrics(start, metrics.ResultRequeueAfter)
+		requeued = true
+	case result.Requeue:
+		c.queue.AddRateLimited(item)
+		c.recordEvent(involvedObject, result, nil)
+		c.recordMetrics(start, metrics.ResultRequeue)
+		requeued = true
+	default:
+		c.queue.Forget(item)
+		c.recordEvent(involvedObject, result, nil)
+		c.forgetObject(item, involvedObject)
+		c.recordMetrics(start, metrics.ResultSuccess)
 	}
+	if requeued {
+		c.keepEvents(item, events)
+	}
+}
 
-	if apierrors.IsNotFound(err) {
+// handleError takes the ErrorAction classified by the errorPolicy on a failed work item. It returns true if the
+// work item is requeued.
+func (c *TypedController[K]) handleError(ctx context.Context, item K, involvedObject runtime.Object, result Result,
+	err error, start time.Time) bool {
+	utilruntime.HandleError(err)
+	c.recordEvent(involvedObject, result, err)
+
+	var requeued bool
+	action := c.classifyError(err)
+	if (action == ErrorActionRetryFast || action == ErrorActionRetryWithBackoff) && c.exceedsMaxRetries(item) {
+		c.deadLetter(ctx, item, involvedObject, err)
+		c.recordMetrics(start, metrics.ResultDeadLetter)
+		return false
+	}
+	switch action {
+	case ErrorActionDrop:
 		c.queue.Forget(item)
-		return true
+		c.failures.forget(item)
+		c.forgetObject(item, involvedObject)
+		if IsTerminalError(err) {
+			c.recordMetrics(start, metrics.ResultTerminalError)
+		} else {
+			c.recordMetrics(start, metrics.ResultDropped)
+		}
+	case ErrorActionRetryFast:
+		c.queue.AddAfter(item, fastRetryDelay(c.failures.inc(item)))
+		c.recordMetrics(start, metrics.ResultError)
+		requeued = true
+	case ErrorActionEscalate:
+		c.queue.Forget(item)
+		c.failures.forget(item)
+		c.recordMetrics(start, metrics.ResultError)
+		c.escalate(err)
+	default:
+		// put the item back on the work queue to handle any transient errors
+		c.failures.inc(item)
+		if result.RequeueAfter > 0 {
+			c.queue.AddAfter(item, result.RequeueAfter)
+		} else {
+			c.queue.AddRateLimited(item)
+		}
+		requeued = true
+		switch {
+		case IsPanicError(err):
+			c.recordMetrics(start, metrics.ResultPanic)
+		case IsReconcileTimeout(err):
+			c.recordMetrics(start, metrics.ResultTimeout)
+		default:
+			c.recordMetrics(start, metrics.ResultError)
+		}
 	}
+	klog.V(4).Infof("controller %s takes action %s on work item %v: %v", c.name, action, item, err)
+	return requeued
+}
 
-	utilruntime.HandleError(err)
-	// put the item back on the work queue to handle any transient errors
-	if requeueAfter != nil {
-		c.queue.AddAfter(item, *requeueAfter)
-	} else {
-		c.queue.AddRateLimited(item)
+// recordMetrics records the metrics of a single reconciliation
+func (c *TypedController[K]) recordMetrics(start time.Time, result string) {
+	m := c.reconcileMetrics
+	resultMetrics := m.forResult(result)
+	metrics.ObserveDuration(resultMetrics.duration, start)
+	resultMetrics.total.Inc()
+	switch result {
+	case metrics.ResultError, metrics.ResultTimeout, metrics.ResultPanic:
+		m.errors.Inc()
+		m.requeues.Inc()
+	case metrics.ResultTerminalError, metrics.ResultDropped, metrics.ResultDeadLetter:
+		m.errors.Inc()
+	case metrics.ResultRequeue, metrics.ResultRequeueAfter:
+		m.requeues.Inc()
+	}
+}
+
+// reconcileMetrics caches the reconcile metrics of a controller, so that they are not looked up on every
+// reconciliation
+type reconcileMetrics struct {
+	provider metrics.Provider
+	name     string
+	errors   metrics.CounterMetric
+	requeues metrics.CounterMetric
+	// results caches the *resultMetrics of each result
+	results sync.Map
+}
+
+// resultMetrics are the reconcile metrics labelled by a result
+type resultMetrics struct {
+	total    metrics.CounterMetric
+	duration metrics.HistogramMetric
+}
+
+func newReconcileMetrics(provider metrics.Provider, name string) *reconcileMetrics {
+	return &reconcileMetrics{
+		provider: provider,
+		name:     name,
+		errors:   provider.NewReconcileErrorsMetric(name),
+		requeues: provider.NewReconcileRequeuesMetric(name),
+	}
+}
+
+// forResult returns the metrics of result, which are created on the first use
+func (m *reconcileMetrics) forResult(result string) *resultMetrics {
+	if value, ok := m.results.Load(result); ok {
+		return value.(*resultMetrics)
+	}
+	value, _ := m.results.LoadOrStore(result, &resultMetrics{
+		total:    m.provider.NewReconcileTotalMetric(m.name, result),
+		duration: m.provider.NewReconcileDurationMetric(m.name, result),
+	})
+	return value.(*resultMetrics)
+}
+
+// newLeaderElector creates a LeaderElector with the callbacks, where OnStartedLeading is required
+func newLeaderElector(name string, leaseLock rl.Interface, leaseDuration, renewDeadline, retryPeriod time.Duration,
+	callbacks leaderelection.LeaderCallbacks) (*leaderelection.LeaderElector, error) {
+	lec := leaderelection.LeaderElectionConfig{
+		Lock: leaseLock,
+		// IMPORTANT: you MUST ensure that any code you have that is protected by the lease must terminate **before**
+		// you call cancel. Otherwise, you could have a background loop still running and another process could
+		// get elected before your background loop finished, violating the stated goal of the lease.
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: callbacks.OnStartedLeading,
+			OnStoppedLeading: func() {
+				klog.Errorf("leader election got lost for %s", name)
+				if callbacks.OnStoppedLeading != nil {
+					callbacks.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if callbacks.OnNewLeader != nil {
+					callbacks.OnNewLeader(identity)
+				}
+				// gets notified when new leader is elected
+				if identity == leaseLock.Identity() {
+					// I just got the lock
+					return
+				}
+				klog.Infof("new leader %s is elected for %s", identity, name)
+			},
+		},
+	}
+
+	return leaderelection.NewLeaderElector(lec)
+}
+
+// leaderContext returns a context for a LeaderElector, which is cancelled only after ctx is done and waitFunc
+// returns. Since the LeaderElector releases the lease as soon as its context is cancelled, this makes sure the
+// work protected by the lease terminates before the lease is released.
+func leaderContext(ctx context.Context, waitFunc func()) (context.Context, context.CancelFunc) {
+	leCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-ctx.Done():
+			waitFunc()
+			cancel()
+		case <-leCtx.Done():
+		}
+	}()
+	return leCtx, cancel
+}
+
+// runGuard tracks whether a run is in progress
+type runGuard struct {
+	lock sync.Mutex
+	done chan struct{}
+}
+
+// enter marks a run in progress and returns the function to mark it finished
+func (g *runGuard) enter() func() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	done := make(chan struct{})
+	g.done = done
+	return func() {
+		close(done)
+	}
+}
+
+// wait blocks until the last run is finished
+func (g *runGuard) wait() {
+	g.lock.Lock()
+	done := g.done
+	g.lock.Unlock()
+
+	if done != nil {
+		<-done
 	}
-	return true
 }
 
 // DefaultEnqueueFunc uses a default namespacedKey as its KeyFunc.
 // The key uses the format <namespace>/<name> unless <namespace> is empty, then
-// it's just <name>.
+// it's just <name>. Tombstones of deleted objects are keyed by their original keys.
 func DefaultEnqueueFunc(obj interface{}) (interface{}, error) {
-	return cache.MetaNamespaceKeyFunc(obj)
+	return cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+}
+
+// defaultTypedEnqueueFunc works like DefaultEnqueueFunc when K is string or interface{}
+func defaultTypedEnqueueFunc[K comparable](obj interface{}) (K, error) {
+	var zero K
+	key, err := DefaultEnqueueFunc(obj)
+	if err != nil {
+		return zero, err
+	}
+
+	k, ok := key.(K)
+	if !ok {
+		return zero, fmt.Errorf("default enqueueFunc can not generate key of type %T, please set enqueueFunc", zero)
+	}
+	return k, nil
+}
+
+// newDefaultQueue creates the default rate limited work queue
+func newDefaultQueue[K comparable](name string) TypedRateLimitingInterface[K] {
+	return NewTypedRateLimitingQueue[K](workqueue.NewRateLimitingQueueWithConfig(
+		workqueue.DefaultControllerRateLimiter(),
+		workqueue.RateLimitingQueueConfig{
+			Name: name,
+		}))
 }
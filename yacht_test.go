@@ -0,0 +1,231 @@
+package yacht_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/metrics"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func succeed(_ context.Context, _ string) (*time.Duration, error) {
+	return nil, nil
+}
+
+// eventually waits up to 5s for cond to be true
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// run runs the controller of h in the background, and returns a func to stop it and wait for it to return
+func run[K comparable](t *testing.T, h *yachttest.Harness[K]) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Controller.Run(ctx)
+	}()
+	eventually(t, "workers to start", func() bool { return h.Controller.Ready() == nil })
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// newRestartableHarness creates a Harness whose controller rebuilds the queue on restart and each leader term
+func newRestartableHarness(handler yacht.TypedHandlerFunc[string]) *yachttest.Harness[string] {
+	h := yachttest.NewHarness[string]("test", handler)
+	h.Controller.WithQueueFunc(func() workqueue.RateLimitingInterface {
+		return workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	})
+	return h
+}
+
+// leaseLock creates a Lease lock acquired as identity
+func leaseLock(client kubernetes.Interface, identity string) *resourcelock.LeaseLock {
+	return &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: "default", Name: "yacht"},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+}
+
+// holdLease makes identity hold the lease for leaseDuration from now
+func holdLease(t *testing.T, client kubernetes.Interface, identity string, leaseDuration time.Duration) {
+	t.Helper()
+	now := metav1.Now()
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       identity,
+		LeaseDurationSeconds: int(leaseDuration.Seconds()),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+	lock := leaseLock(client, identity)
+	if _, _, err := lock.Get(context.Background()); err != nil {
+		if err = lock.Create(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := lock.Update(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockLeaseUpdates fails the updates of the leases while the returned flag is set, so that the leader loses the
+// lease once the renew deadline is reached. It must be called before the client is used, since the reactors can not
+// be changed concurrently.
+func blockLeaseUpdates(client *fake.Clientset) *atomic.Bool {
+	var blocked atomic.Bool
+	client.PrependReactor("update", "leases", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		if blocked.Load() {
+			return true, nil, errors.New("blocked")
+		}
+		return false, nil, nil
+	})
+	return &blocked
+}
+
+func TestControllerRecordsReconcileMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	h := yachttest.NewHarness[string]("metrics", func(_ context.Context, key string) (*time.Duration, error) {
+		if key == "b" {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	h.Controller.WithMetricsProvider(registry)
+	h.Add(namespace("a"))
+	h.Add(namespace("b"))
+	h.ProcessAll(context.Background())
+	h.Add(namespace("a"))
+	h.ProcessAll(context.Background())
+
+	var sb strings.Builder
+	if _, err := registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`yacht_reconcile_total{controller="metrics",result="success"} 2`,
+		`yacht_reconcile_total{controller="metrics",result="error"} 1`,
+		`yacht_reconcile_errors_total{controller="metrics"} 1`,
+		`yacht_reconcile_requeues_total{controller="metrics"} 1`,
+		`yacht_reconcile_duration_seconds_count{controller="metrics",result="success"} 2`,
+	} {
+		if !strings.Contains(sb.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, sb.String())
+		}
+	}
+}
+
+// runUntilCancelled runs the controller of h until the returned func is called, which waits for Run to return
+func runUntilCancelled[K comparable](h *yachttest.Harness[K]) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Controller.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestDrainInFlightWorkItems(t *testing.T) {
+	tests := []struct {
+		name         string
+		drainTimeout time.Duration
+		handleTime   time.Duration
+		cancelled    bool
+	}{
+		{name: "without drain timeout", handleTime: time.Minute, cancelled: true},
+		{name: "drained", drainTimeout: 5 * time.Second, handleTime: 100 * time.Millisecond},
+		{name: "timed out", drainTimeout: 100 * time.Millisecond, handleTime: time.Minute, cancelled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			var finished, cancelled bool
+			h := yachttest.NewHarness[string]("test", func(ctx context.Context, _ string) (*time.Duration, error) {
+				close(started)
+				select {
+				case <-time.After(tt.handleTime):
+					finished = true
+				case <-ctx.Done():
+					cancelled = true
+				}
+				return nil, nil
+			})
+			h.Controller.WithDrainTimeout(tt.drainTimeout)
+			stop := runUntilCancelled(h)
+			h.Add(namespace("a"))
+			<-started
+
+			start := time.Now()
+			stop()
+			if cancelled != tt.cancelled || finished == tt.cancelled {
+				t.Fatalf("expected the handler to be cancelled %v, got cancelled %v and finished %v",
+					tt.cancelled, cancelled, finished)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("took %v to stop the controller", elapsed)
+			}
+		})
+	}
+}
+
+func TestDrainStopsPickingUpWorkItems(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	})
+	h.Controller.WithWorkers(1).WithDrainTimeout(5 * time.Second)
+	stop := runUntilCancelled(h)
+	h.Add(namespace("a"))
+	<-started
+	h.Add(namespace("b"))
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		stop()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := h.Controller.Healthy(); err != nil {
+		t.Fatalf("expected the controller to be healthy while draining, got %v", err)
+	}
+	close(release)
+	<-stopped
+	if keys := h.CalledKeys(); len(keys) != 1 || keys[0] != "a" {
+		t.Fatalf("expected only the in-flight work item to be processed, got %v", keys)
+	}
+}
@@ -0,0 +1,152 @@
+package yachttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/dixudx/yacht"
+)
+
+// HandlerCall records a single call of the handler
+type HandlerCall[K comparable] struct {
+	Key    K
+	Result yacht.Result
+	Err    error
+}
+
+// Harness wires a controller to a fake clientset and informer factory, so that controllers can be unit tested
+// without a cluster. Events are injected synchronously and work items are processed one at a time.
+type Harness[K comparable] struct {
+	// Client is the fake clientset seeded with the initial objects
+	Client *fake.Clientset
+	// InformerFactory is the informer factory backed by Client
+	InformerFactory informers.SharedInformerFactory
+	// Controller is the controller under test
+	Controller *yacht.TypedController[K]
+	// Queue is the deterministic work queue used by Controller
+	Queue *Queue
+
+	lock  sync.Mutex
+	calls []HandlerCall[K]
+}
+
+// NewHarness creates a Harness for a TypedController with the given handler. The fake clientset is seeded with
+// objects. Controller can be further configured, e.g. with WithEnqueueFunc or WithEnqueueFilterFunc.
+func NewHarness[K comparable](name string, handler yacht.TypedHandlerFunc[K], objects ...runtime.Object) *Harness[K] {
+	return NewReconcileHarness[K](name, yacht.ReconcileFuncFor(handler), objects...)
+}
+
+// NewReconcileHarness works like NewHarness with a handler returning a yacht.Result
+func NewReconcileHarness[K comparable](name string, reconcileFunc yacht.TypedReconcileFunc[K],
+	objects ...runtime.Object) *Harness[K] {
+	client := fake.NewSimpleClientset(objects...)
+	queue := NewQueue()
+	h := &Harness[K]{
+		Client:          client,
+		InformerFactory: informers.NewSharedInformerFactory(client, 0),
+		Queue:           queue,
+	}
+	h.Controller = yacht.NewTypedController[K](name).
+		WithQueue(queue).
+		WithReconcileFunc(func(ctx context.Context, key K) (yacht.Result, error) {
+			result, err := reconcileFunc(ctx, key)
+			h.lock.Lock()
+			defer h.lock.Unlock()
+			h.calls = append(h.calls, HandlerCall[K]{Key: key, Result: result, Err: err})
+			return result, err
+		})
+	return h
+}
+
+// NewControllerHarness creates a Harness for an untyped Controller
+func NewControllerHarness(name string, handler yacht.HandlerContextFunc, objects ...runtime.Object) *Harness[interface{}] {
+	return NewHarness[interface{}](name, handler, objects...)
+}
+
+// Start starts the informer factory and waits for all the requested informers to be synced, so that listers
+// obtained from InformerFactory see the seeded objects.
+func (h *Harness[K]) Start(ctx context.Context) error {
+	h.InformerFactory.Start(ctx.Done())
+	for informerType, synced := range h.InformerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to sync informer for %v", informerType)
+		}
+	}
+	return nil
+}
+
+// Add injects an add event into the controller
+func (h *Harness[K]) Add(obj interface{}) {
+	h.Controller.DefaultResourceEventHandlerFuncs().OnAdd(obj, false)
+}
+
+// Update injects an update event into the controller
+func (h *Harness[K]) Update(oldObj, newObj interface{}) {
+	h.Controller.DefaultResourceEventHandlerFuncs().OnUpdate(oldObj, newObj)
+}
+
+// Delete injects a delete event into the controller
+func (h *Harness[K]) Delete(obj interface{}) {
+	h.Controller.DefaultResourceEventHandlerFuncs().OnDelete(obj)
+}
+
+// AddEventHandler registers the handler of the controller to an informer, e.g. one from InformerFactory,
+// so that changes made through Client are delivered as events once the informer is started.
+func (h *Harness[K]) AddEventHandler(informer cache.SharedIndexInformer) error {
+	_, err := informer.AddEventHandler(h.Controller.DefaultResourceEventHandlerFuncs())
+	return err
+}
+
+// ProcessNext processes a single work item off the queue synchronously. It returns false if the queue is empty.
+func (h *Harness[K]) ProcessNext(ctx context.Context) bool {
+	if h.Queue.Len() == 0 {
+		return false
+	}
+	return h.Controller.ProcessNextWorkItem(ctx)
+}
+
+// ProcessAll processes all the work items off the queue until it is empty, and returns the number of processed
+// items. Requeued items are held back in Queue until Flush is called.
+func (h *Harness[K]) ProcessAll(ctx context.Context) int {
+	var count int
+	for h.ProcessNext(ctx) {
+		count++
+	}
+	return count
+}
+
+// Flush adds all the requeued items back onto the queue
+func (h *Harness[K]) Flush() {
+	h.Queue.Flush()
+}
+
+// Calls returns all the handler calls in order
+func (h *Harness[K]) Calls() []HandlerCall[K] {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]HandlerCall[K]{}, h.calls...)
+}
+
+// CalledKeys returns the keys of all the handler calls in order
+func (h *Harness[K]) CalledKeys() []K {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	keys := make([]K, 0, len(h.calls))
+	for _, call := range h.calls {
+		keys = append(keys, call.Key)
+	}
+	return keys
+}
+
+// Reset clears the recorded handler calls
+func (h *Harness[K]) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.calls = nil
+}
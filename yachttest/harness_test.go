@@ -0,0 +1,168 @@
+package yachttest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/dixudx/yacht"
+	"github.com/dixudx/yacht/yachttest"
+)
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func succeed(_ context.Context, _ string) (*time.Duration, error) {
+	return nil, nil
+}
+
+func TestHarnessProcessesInjectedEvents(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed)
+	h.Add(namespace("a"))
+	h.Add(namespace("b"))
+	// deduplicated with the pending work item
+	h.Update(namespace("a"), namespace("a"))
+
+	if n := h.ProcessAll(context.Background()); n != 2 {
+		t.Fatalf("expected 2 processed work items, got %d", n)
+	}
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf("unexpected called keys %v", keys)
+	}
+
+	h.Reset()
+	h.Delete(namespace("a"))
+	h.ProcessAll(context.Background())
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("unexpected called keys %v after deletion", keys)
+	}
+}
+
+func TestHarnessHoldsBackRetries(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "namespaces"}, "a", errors.New("changed"))
+	tests := []struct {
+		name        string
+		result      yacht.Result
+		err         error
+		rateLimited int
+		delayed     int
+	}{
+		{name: "error", err: errors.New("failed"), rateLimited: 1},
+		{name: "conflict", err: conflict, delayed: 1},
+		{name: "error with requeueAfter", result: yacht.Result{RequeueAfter: time.Minute}, err: errors.New("failed"), delayed: 1},
+		{name: "requeue", result: yacht.Result{Requeue: true}, rateLimited: 1},
+		{name: "requeueAfter", result: yacht.Result{RequeueAfter: time.Minute}, delayed: 1},
+		{name: "terminal error", err: yacht.TerminalError(errors.New("failed"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := yachttest.NewReconcileHarness[string]("test", func(_ context.Context, _ string) (yacht.Result, error) {
+				return tt.result, tt.err
+			})
+			h.Add(namespace("a"))
+			if n := h.ProcessAll(context.Background()); n != 1 {
+				t.Fatalf("expected 1 processed work item, got %d", n)
+			}
+
+			for i := 0; i < 3; i++ {
+				if got := len(h.Queue.RateLimited()); got != tt.rateLimited {
+					t.Fatalf("expected %d rate limited work items, got %d", tt.rateLimited, got)
+				}
+				if got := len(h.Queue.Delayed()); got != tt.delayed {
+					t.Fatalf("expected %d delayed work items, got %d", tt.delayed, got)
+				}
+				h.Flush()
+				if n := h.ProcessAll(context.Background()); n != tt.rateLimited+tt.delayed {
+					t.Fatalf("expected %d processed work items after flushing, got %d", tt.rateLimited+tt.delayed, n)
+				}
+			}
+		})
+	}
+}
+
+func TestHarnessFastRetriesBackOff(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "namespaces"}, "a", errors.New("changed"))
+	h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+		return nil, conflict
+	})
+	h.Add(namespace("a"))
+
+	var last time.Duration
+	for i := 0; i < 5; i++ {
+		h.ProcessAll(context.Background())
+		delayed := h.Queue.Delayed()
+		if len(delayed) != 1 {
+			t.Fatalf("expected 1 delayed work item, got %v", delayed)
+		}
+		if delayed[0].Duration <= last {
+			t.Fatalf("expected the delay to grow from %v, got %v", last, delayed[0].Duration)
+		}
+		last = delayed[0].Duration
+		h.Flush()
+	}
+}
+
+func TestHarnessDeadLetter(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "namespaces"}, "a", errors.New("changed"))
+	for _, err := range []error{errors.New("failed"), conflict} {
+		var deadLetters []string
+		h := yachttest.NewHarness[string]("test", func(_ context.Context, _ string) (*time.Duration, error) {
+			return nil, err
+		})
+		h.Controller.WithMaxRetries(3, func(_ context.Context, key string, _ error) {
+			deadLetters = append(deadLetters, key)
+		})
+		h.Add(namespace("a"))
+
+		for i := 0; i < 10; i++ {
+			h.ProcessAll(context.Background())
+			h.Flush()
+		}
+		if calls := len(h.Calls()); calls != 4 {
+			t.Errorf("expected 4 calls for %v, got %d", err, calls)
+		}
+		if !reflect.DeepEqual(deadLetters, []string{"a"}) {
+			t.Errorf("unexpected dead letters %v for %v", deadLetters, err)
+		}
+	}
+}
+
+func TestHarnessInformerEvents(t *testing.T) {
+	h := yachttest.NewHarness[string]("test", succeed, namespace("a"))
+	if err := h.AddEventHandler(h.InformerFactory.Core().V1().Namespaces().Informer()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := h.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	h.ProcessAll(ctx)
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("unexpected called keys %v for the seeded objects", keys)
+	}
+
+	h.Reset()
+	if _, err := h.Client.CoreV1().Namespaces().Create(ctx, namespace("b"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Queue.Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the event of the created object")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.ProcessAll(ctx)
+	if keys := h.CalledKeys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("unexpected called keys %v for the created object", keys)
+	}
+}
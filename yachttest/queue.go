@@ -0,0 +1,94 @@
+package yachttest
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// DelayedItem is a work item added with AddAfter
+type DelayedItem struct {
+	Item     interface{}
+	Duration time.Duration
+}
+
+// Queue is a deterministic workqueue.RateLimitingInterface for tests. Items added with AddAfter or AddRateLimited,
+// which is how the controller retries failed work items, are recorded and held back until Flush is called, so that
+// failing handlers never loop forever.
+type Queue struct {
+	workqueue.Interface
+
+	lock        sync.Mutex
+	numRequeues map[interface{}]int
+	rateLimited []interface{}
+	delayed     []DelayedItem
+}
+
+var _ workqueue.RateLimitingInterface = &Queue{}
+
+// NewQueue creates a new Queue
+func NewQueue() *Queue {
+	return &Queue{
+		Interface:   workqueue.New(),
+		numRequeues: map[interface{}]int{},
+	}
+}
+
+// AddAfter records the item, which will be added when Flush is called
+func (q *Queue) AddAfter(item interface{}, duration time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.delayed = append(q.delayed, DelayedItem{Item: item, Duration: duration})
+}
+
+// AddRateLimited records the item and increases its requeue count. The item will be added when Flush is called.
+func (q *Queue) AddRateLimited(item interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.numRequeues[item]++
+	q.rateLimited = append(q.rateLimited, item)
+}
+
+// Forget resets the requeue count of the item
+func (q *Queue) Forget(item interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.numRequeues, item)
+}
+
+// NumRequeues returns how many times the item has been added with AddRateLimited since the last Forget
+func (q *Queue) NumRequeues(item interface{}) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.numRequeues[item]
+}
+
+// RateLimited returns all the items added with AddRateLimited which have not been flushed
+func (q *Queue) RateLimited() []interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]interface{}{}, q.rateLimited...)
+}
+
+// Delayed returns all the items added with AddAfter which have not been flushed
+func (q *Queue) Delayed() []DelayedItem {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]DelayedItem{}, q.delayed...)
+}
+
+// Flush adds all the rate limited and delayed items onto the queue immediately
+func (q *Queue) Flush() {
+	q.lock.Lock()
+	rateLimited, delayed := q.rateLimited, q.delayed
+	q.rateLimited, q.delayed = nil, nil
+	q.lock.Unlock()
+
+	for _, item := range rateLimited {
+		q.Add(item)
+	}
+	for _, d := range delayed {
+		q.Add(d.Item)
+	}
+}
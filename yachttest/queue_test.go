@@ -0,0 +1,32 @@
+package yachttest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueueHoldsBackRequeuedItems(t *testing.T) {
+	q := NewQueue()
+	q.AddRateLimited("a")
+	q.AddRateLimited("a")
+	q.AddAfter("b", time.Minute)
+	if q.Len() != 0 {
+		t.Fatalf("expected requeued items to be held back, got %d items", q.Len())
+	}
+	if n := q.NumRequeues("a"); n != 2 {
+		t.Fatalf("expected 2 requeues, got %d", n)
+	}
+	if delayed := q.Delayed(); !reflect.DeepEqual(delayed, []DelayedItem{{Item: "b", Duration: time.Minute}}) {
+		t.Fatalf("unexpected delayed items %v", delayed)
+	}
+
+	q.Flush()
+	if q.Len() != 2 || len(q.RateLimited()) != 0 || len(q.Delayed()) != 0 {
+		t.Fatalf("expected the held back items to be added, got %d items", q.Len())
+	}
+	q.Forget("a")
+	if n := q.NumRequeues("a"); n != 0 {
+		t.Fatalf("expected the requeues to be forgotten, got %d", n)
+	}
+}